@@ -0,0 +1,475 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ankane/pgslice-go/pgslice"
+	"github.com/urfave/cli"
+)
+
+func Prep(ctx *cli.Context) error {
+	return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+		return pgslice.Prep(c, db, pgslice.PrepOptions{
+			Table:            table.Name,
+			Column:           cmp.Or(ctx.Args().Get(1), table.Column),
+			Period:           cmp.Or(ctx.Args().Get(2), table.Period),
+			Interval:         Int64Option(ctx, "interval", table.Interval),
+			Strategy:         cmp.Or(ctx.String("strategy"), table.Strategy),
+			SubColumn:        cmp.Or(ctx.String("sub-column"), table.SubColumn),
+			SubModulus:       IntOption(ctx, "sub-modulus", table.SubModulus),
+			DefaultPartition: ctx.Bool("default-partition") || table.DefaultPartition,
+			TimeZone:         cmp.Or(ctx.String("time-zone"), table.TimeZone),
+			Cast:             cmp.Or(ctx.String("cast"), table.Cast),
+			NameTemplate:     cmp.Or(ctx.String("name-template"), table.NameTemplate),
+			PartitionSchema:  cmp.Or(ctx.String("partition-schema"), table.PartitionSchema),
+			NoPartition:      ctx.Bool("no-partition"),
+			TriggerBased:     ctx.Bool("trigger-based"),
+		})
+	})
+}
+
+func AddPartitions(ctx *cli.Context) error {
+	return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+		values := table.Values
+		if ctx.String("values") != "" {
+			values = strings.Split(ctx.String("values"), ",")
+		}
+
+		return pgslice.AddPartitions(c, db, pgslice.AddPartitionsOptions{
+			Table:            table.Name,
+			Intermediate:     ctx.Bool("intermediate"),
+			Past:             IntOption(ctx, "past", table.Past),
+			Future:           IntOption(ctx, "future", table.Future),
+			Modulus:          IntOption(ctx, "modulus", table.Modulus),
+			Values:           values,
+			DefaultPartition: ctx.Bool("default-partition") || table.DefaultPartition,
+		})
+	})
+}
+
+func Prune(ctx *cli.Context) error {
+	if ctx.Bool("detach") && ctx.Bool("drop") {
+		return Abort("Can't use --detach and --drop")
+	}
+
+	return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+		plan, err := pgslice.Prune(c, db, pgslice.PruneOptions{
+			Table: table.Name,
+			Keep:  IntOption(ctx, "keep", table.Keep),
+			Drop:  ctx.Bool("drop") || (table.Drop && !ctx.Bool("detach")),
+		})
+		// keep the plan file the only output
+		if err == nil && plan.Empty() && ctx.String("plan-out") == "" {
+			fmt.Printf("/* nothing to prune for %s */\n", table.Name)
+		}
+		return plan, err
+	})
+}
+
+func Rescue(ctx *cli.Context) error {
+	return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+		plan, err := pgslice.Rescue(c, db, pgslice.RescueOptions{
+			Table:        table.Name,
+			Intermediate: ctx.Bool("intermediate"),
+		})
+		if err == nil && plan.Empty() && ctx.String("plan-out") == "" {
+			fmt.Printf("/* nothing to rescue for %s */\n", table.Name)
+		}
+		return plan, err
+	})
+}
+
+func Attach(ctx *cli.Context) error {
+	return RunPlan(ctx, func(c context.Context, db *sql.DB) (*pgslice.Plan, error) {
+		return pgslice.Attach(c, db, pgslice.AttachOptions{
+			Table:        ctx.Args().Get(0),
+			Child:        ctx.Args().Get(1),
+			From:         ctx.String("from"),
+			To:           ctx.String("to"),
+			Intermediate: ctx.Bool("intermediate"),
+		})
+	})
+}
+
+func FillOptions(ctx *cli.Context, table TableConfig) pgslice.FillOptions {
+	return pgslice.FillOptions{
+		Table:       table.Name,
+		BatchSize:   IntOption(ctx, "batch-size", table.BatchSize),
+		Swapped:     ctx.Bool("swapped"),
+		SourceTable: ctx.String("source-table"),
+		DestTable:   ctx.String("dest-table"),
+		Start:       ctx.Int("start"),
+		Where:       cmp.Or(ctx.String("where"), table.Where),
+		Sleep:       time.Duration(IntOption(ctx, "sleep", table.Sleep)) * time.Second,
+		Jobs:        ctx.Int("jobs"),
+		Resume:      ctx.Bool("resume"),
+		DryRun:      ctx.Bool("dry-run"),
+		Log:         os.Stdout,
+
+		MaxReplicaLag: ctx.Duration("max-replica-lag"),
+		ReplicaURLs:   ctx.StringSlice("replica-url"),
+		MaxWALRate:    ctx.Int64("max-wal-rate") * 1024 * 1024,
+	}
+}
+
+func Fill(ctx *cli.Context) error {
+	if ctx.Bool("reset-checkpoint") {
+		return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+			return pgslice.ResetFillCheckpoint(c, db, FillOptions(ctx, table))
+		})
+	}
+
+	tables, err := Tables(ctx)
+	if err != nil {
+		return err
+	}
+
+	db, err := Connection(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if ctx.Bool("show-checkpoint") {
+		for _, table := range tables {
+			checkpoint, err := pgslice.FillCheckpoint(context.Background(), db, FillOptions(ctx, table))
+			if err != nil {
+				return HandleError(err)
+			}
+			PrintCheckpoint(checkpoint)
+		}
+		return nil
+	}
+
+	// one connection per job plus one to find batches
+	db.SetMaxOpenConns(max(ctx.Int("jobs"), 1) + 1)
+
+	for _, table := range tables {
+		err := pgslice.Fill(context.Background(), db, FillOptions(ctx, table))
+		if err != nil {
+			return HandleError(err)
+		}
+	}
+	return nil
+}
+
+func PrintCheckpoint(checkpoint *pgslice.Checkpoint) {
+	if checkpoint == nil {
+		fmt.Println("No checkpoint")
+		return
+	}
+
+	fmt.Printf("Source: %s\n", checkpoint.SourceTable)
+	fmt.Printf("Destination: %s\n", checkpoint.Table)
+	fmt.Printf("Last key: %s\n", strings.Join(checkpoint.LastKey, ", "))
+	fmt.Printf("Batches: %d\n", checkpoint.BatchCount)
+	fmt.Printf("Started at: %s\n", checkpoint.StartedAt.UTC().Format(time.RFC3339))
+	fmt.Printf("Updated at: %s\n", checkpoint.UpdatedAt.UTC().Format(time.RFC3339))
+}
+
+func RepartitionOptions(ctx *cli.Context) pgslice.RepartitionOptions {
+	return pgslice.RepartitionOptions{
+		Table:       ctx.Args().Get(0),
+		From:        ctx.String("from"),
+		To:          ctx.String("to"),
+		Period:      ctx.String("period"),
+		BatchSize:   ctx.Int("batch-size"),
+		Sleep:       time.Duration(ctx.Int("sleep")) * time.Second,
+		LockTimeout: ctx.String("lock-timeout"),
+		Drop:        ctx.Bool("drop"),
+		DryRun:      ctx.Bool("dry-run"),
+		Log:         os.Stdout,
+	}
+}
+
+func Split(ctx *cli.Context) error {
+	db, err := Connection(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return HandleError(pgslice.Split(context.Background(), db, RepartitionOptions(ctx)))
+}
+
+func Merge(ctx *cli.Context) error {
+	db, err := Connection(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return HandleError(pgslice.Merge(context.Background(), db, RepartitionOptions(ctx)))
+}
+
+func Sync(ctx *cli.Context) error {
+	return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+		return pgslice.Sync(c, db, pgslice.SyncOptions{Table: table.Name})
+	})
+}
+
+func Analyze(ctx *cli.Context) error {
+	return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+		return pgslice.Analyze(c, db, pgslice.AnalyzeOptions{
+			Table:   table.Name,
+			Swapped: ctx.Bool("swapped"),
+		})
+	})
+}
+
+func Swap(ctx *cli.Context) error {
+	return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+		return pgslice.Swap(c, db, pgslice.SwapOptions{
+			Table:       table.Name,
+			LockTimeout: ctx.String("lock-timeout"),
+		})
+	})
+}
+
+func Upgrade(ctx *cli.Context) error {
+	return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+		return pgslice.Upgrade(c, db, pgslice.UpgradeOptions{
+			Table:       table.Name,
+			LockTimeout: ctx.String("lock-timeout"),
+		})
+	})
+}
+
+func Unpartition(ctx *cli.Context) error {
+	tables, err := Tables(ctx)
+	if err != nil {
+		return err
+	}
+
+	db, err := Connection(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, table := range tables {
+		err := pgslice.Unpartition(context.Background(), db, pgslice.UnpartitionOptions{
+			Table:       table.Name,
+			BatchSize:   IntOption(ctx, "batch-size", table.BatchSize),
+			Sleep:       time.Duration(IntOption(ctx, "sleep", table.Sleep)) * time.Second,
+			LockTimeout: ctx.String("lock-timeout"),
+			DryRun:      ctx.Bool("dry-run"),
+			Log:         os.Stdout,
+		})
+		if err != nil {
+			return HandleError(err)
+		}
+	}
+	return nil
+}
+
+func Status(ctx *cli.Context) error {
+	format := ctx.String("format")
+	if format != "text" && format != "json" {
+		return Abort("Invalid format: " + format)
+	}
+
+	tables, err := Tables(ctx)
+	if err != nil {
+		return err
+	}
+
+	db, err := Connection(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var statuses []*pgslice.StatusReport
+	for _, table := range tables {
+		status, err := pgslice.Status(context.Background(), db, pgslice.StatusOptions{Table: table.Name})
+		if err != nil {
+			return HandleError(err)
+		}
+		statuses = append(statuses, status)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		// an array for --all
+		if ctx.Bool("all") {
+			return encoder.Encode(statuses)
+		}
+		return encoder.Encode(statuses[0])
+	}
+
+	for i, status := range statuses {
+		if i > 0 {
+			fmt.Println()
+		}
+		PrintStatus(status)
+	}
+	return nil
+}
+
+func PrintStatus(status *pgslice.StatusReport) {
+	existsStr := func(exists bool) string {
+		if exists {
+			return "exists"
+		}
+		return "missing"
+	}
+
+	fmt.Printf("Step: %s\n", status.Step)
+	fmt.Printf("Table: %s (%s)\n", status.Table.Name, existsStr(status.Table.Exists))
+	fmt.Printf("Intermediate table: %s (%s)\n", status.IntermediateTable.Name, existsStr(status.IntermediateTable.Exists))
+	fmt.Printf("Retired table: %s (%s)\n", status.RetiredTable.Name, existsStr(status.RetiredTable.Exists))
+
+	if status.Partitioning != "" {
+		fmt.Printf("Partitioning: %s\n", status.Partitioning)
+		fmt.Printf("Strategy: %s\n", status.Strategy)
+		fmt.Printf("Column: %s\n", status.Column)
+		if status.Interval > 0 {
+			fmt.Printf("Interval: %d\n", status.Interval)
+		} else if status.Period != "" {
+			fmt.Printf("Period: %s\n", status.Period)
+			for _, r := range status.PeriodRanges {
+				fmt.Printf("  %s from %s to %s\n", r.Period, r.From, r.To)
+			}
+		}
+		fmt.Printf("Cast: %s\n", status.Cast)
+		if status.TimeZone != "" {
+			fmt.Printf("Time zone: %s\n", status.TimeZone)
+		}
+		if status.NameTemplate != "" {
+			fmt.Printf("Name template: %s\n", status.NameTemplate)
+		}
+		if status.PartitionSchema != "" {
+			fmt.Printf("Partition schema: %s\n", status.PartitionSchema)
+		}
+		if status.SubColumn != "" {
+			fmt.Printf("Sub-partitioning: hash (%s, modulus %d)\n", status.SubColumn, status.SubModulus)
+		}
+		fmt.Printf("Partitions: %d\n", len(status.Partitions))
+		for _, partition := range status.Partitions {
+			if partition.From == "" {
+				fmt.Printf("  %s\n", partition.Name)
+				continue
+			}
+			fmt.Printf("  %s: %s to %s\n", partition.Name, partition.From, partition.To)
+		}
+		fmt.Printf("Future partitions: %d\n", status.FuturePartitions)
+	}
+
+	if status.Syncing {
+		fmt.Println("Syncing: yes")
+	}
+
+	if status.Fill != nil {
+		fmt.Printf("Fill: ~%.0f%% (~%d of ~%d rows from %s)\n", status.Fill.Progress*100, status.Fill.DestRows, status.Fill.SourceRows, status.Fill.SourceTable)
+		if status.Fill.LastKey != nil {
+			fmt.Printf("Checkpoint: %d batches\n", status.Fill.BatchesSaved)
+		}
+	}
+}
+
+func Settings(ctx *cli.Context) error {
+	set := make(map[string]string)
+	for _, setting := range ctx.StringSlice("set") {
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return Abort("Invalid setting: " + setting)
+		}
+		set[key] = value
+	}
+
+	if len(set) > 0 {
+		return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+			return pgslice.UpdateSettings(c, db, pgslice.SettingsOptions{Table: table.Name, Set: set})
+		})
+	}
+
+	tables, err := Tables(ctx)
+	if err != nil {
+		return err
+	}
+
+	db, err := Connection(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for i, table := range tables {
+		settings, err := pgslice.TableSettings(context.Background(), db, table.Name)
+		if err != nil {
+			return HandleError(err)
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+		err = PrintSettings(table.Name, settings)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrintSettings prints settings in the order of pgslice.SettingKeys
+func PrintSettings(name string, settings pgslice.Settings) error {
+	var values map[string]any
+	// keys and values match the JSON saved in the comment
+	decoder := json.NewDecoder(strings.NewReader(settings.Comment()))
+	decoder.UseNumber()
+	err := decoder.Decode(&values)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("table: %s\n", name)
+	for _, key := range pgslice.SettingKeys {
+		value, ok := values[key]
+		if ok {
+			fmt.Printf("%s: %v\n", key, value)
+		}
+	}
+	return nil
+}
+
+func Apply(ctx *cli.Context) error {
+	path := ctx.Args().Get(0)
+	if path == "" {
+		return Abort("Usage: pgslice apply PLAN")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	plan, err := pgslice.ParsePlan(data)
+	if err != nil {
+		return Abort(fmt.Sprintf("Invalid plan: %s", err))
+	}
+
+	return RunPlan(ctx, func(c context.Context, db *sql.DB) (*pgslice.Plan, error) {
+		return plan, nil
+	})
+}
+
+func Unprep(ctx *cli.Context) error {
+	return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+		return pgslice.Unprep(c, db, pgslice.UnprepOptions{Table: table.Name})
+	})
+}
+
+func Unswap(ctx *cli.Context) error {
+	return RunTablePlans(ctx, func(c context.Context, db *sql.DB, table TableConfig) (*pgslice.Plan, error) {
+		return pgslice.Unswap(c, db, pgslice.UnswapOptions{Table: table.Name})
+	})
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	URL      string                   `yaml:"url"`
+	Profiles map[string]ProfileConfig `yaml:"profiles"`
+	Interval string                   `yaml:"interval"`
+	Tables   []TableConfig            `yaml:"tables"`
+}
+
+type ProfileConfig struct {
+	URL string `yaml:"url"`
+}
+
+type TableConfig struct {
+	Name             string   `yaml:"name"`
+	Column           string   `yaml:"column"`
+	Period           string   `yaml:"period"`
+	Interval         int64    `yaml:"interval"`
+	TimeZone         string   `yaml:"time_zone"`
+	Cast             string   `yaml:"cast"`
+	NameTemplate     string   `yaml:"name_template"`
+	PartitionSchema  string   `yaml:"partition_schema"`
+	Strategy         string   `yaml:"strategy"`
+	SubColumn        string   `yaml:"sub_column"`
+	SubModulus       int      `yaml:"sub_modulus"`
+	Past             int      `yaml:"past"`
+	Future           int      `yaml:"future"`
+	Modulus          int      `yaml:"modulus"`
+	Values           []string `yaml:"values"`
+	DefaultPartition bool     `yaml:"default_partition"`
+	BatchSize        int      `yaml:"batch_size"`
+	Sleep            int      `yaml:"sleep"`
+	Where            string   `yaml:"where"`
+	Keep             int      `yaml:"keep"`
+	Drop             bool     `yaml:"drop"`
+	Analyze          bool     `yaml:"analyze"`
+}
+
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	err = yaml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid config: %s", err)
+	}
+
+	// allow secrets to come from the environment
+	config.URL = os.ExpandEnv(config.URL)
+	for name, profile := range config.Profiles {
+		profile.URL = os.ExpandEnv(profile.URL)
+		config.Profiles[name] = profile
+	}
+
+	if config.Interval == "" {
+		config.Interval = "1h"
+	}
+	_, err = config.IntervalDuration()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid interval: %s", config.Interval)
+	}
+
+	names := make(map[string]bool)
+	for _, table := range config.Tables {
+		if table.Name == "" {
+			return nil, fmt.Errorf("Table name required")
+		}
+		if names[table.Name] {
+			return nil, fmt.Errorf("Duplicate table: %s", table.Name)
+		}
+		names[table.Name] = true
+	}
+
+	return &config, nil
+}
+
+// ReadConfig loads --config, which is optional unless passed explicitly
+// or needed for --profile, --all, or --table
+func ReadConfig(ctx *cli.Context) (*Config, error) {
+	path := ctx.String("config")
+	required := ctx.IsSet("config") || ctx.String("profile") != "" || ctx.Bool("all") || ctx.String("table") != ""
+
+	config, err := LoadConfig(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, Abort(err.Error())
+	}
+	return config, nil
+}
+
+func (c Config) IntervalDuration() (time.Duration, error) {
+	return time.ParseDuration(c.Interval)
+}
+
+// ProfileURL returns the URL for a profile, or the top-level URL
+func (c Config) ProfileURL(name string) (string, error) {
+	if name == "" {
+		return c.URL, nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return "", fmt.Errorf("Unknown profile: %s", name)
+	}
+	return profile.URL, nil
+}
+
+func (c Config) Table(name string) (TableConfig, error) {
+	for _, table := range c.Tables {
+		if table.Name == name {
+			return table, nil
+		}
+	}
+	return TableConfig{}, fmt.Errorf("Table not in config: %s", name)
+}
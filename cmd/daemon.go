@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ankane/pgslice-go/pgslice"
+	"github.com/urfave/cli"
+)
+
+// key for pg_try_advisory_lock so only one daemon acts at a time
+const DaemonLockKey int64 = 7_010_415_223_817
+
+func Daemon(ctx *cli.Context) error {
+	config, err := LoadConfig(ctx.String("config"))
+	if err != nil {
+		return Abort(err.Error())
+	}
+
+	interval, err := config.IntervalDuration()
+	if err != nil {
+		return err
+	}
+
+	db, err := Open(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for {
+		ok := RunMaintenance(c, db, config, ExecuteOptions(ctx))
+		if ctx.Bool("once") {
+			if !ok {
+				return Abort("Maintenance failed")
+			}
+			return nil
+		}
+
+		select {
+		case <-c.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RunMaintenance adds partitions, prunes, and analyzes each table in the config.
+// It returns false if any task failed.
+func RunMaintenance(c context.Context, db *sql.DB, config *Config, opts pgslice.ExecuteOptions) bool {
+	conn, err := db.Conn(c)
+	if err != nil {
+		log.Printf("Error: %s", err)
+		return false
+	}
+	defer conn.Close()
+
+	var locked bool
+	err = conn.QueryRowContext(c, "SELECT pg_try_advisory_lock($1)", DaemonLockKey).Scan(&locked)
+	if err != nil {
+		log.Printf("Error: %s", err)
+		return false
+	}
+	if !locked {
+		log.Println("Another instance holds the lock, skipping")
+		return true
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", DaemonLockKey)
+
+	ok := true
+	for _, table := range config.Tables {
+		run := func(task string, build func() (*pgslice.Plan, error)) {
+			plan, err := build()
+			if err == nil {
+				err = plan.Execute(c, db, opts)
+			}
+
+			if err != nil && c.Err() != nil {
+				// shutting down
+				return
+			}
+			if err != nil {
+				log.Printf("%s %s: failed: %s", task, table.Name, err)
+				ok = false
+			} else {
+				log.Printf("%s %s: ok (%d steps)", task, table.Name, len(plan.Steps))
+			}
+		}
+
+		run("add_partitions", func() (*pgslice.Plan, error) {
+			return pgslice.AddPartitions(c, db, pgslice.AddPartitionsOptions{Table: table.Name, Past: table.Past, Future: table.Future, Modulus: table.Modulus, Values: table.Values, DefaultPartition: table.DefaultPartition})
+		})
+
+		if table.Keep > 0 {
+			run("prune", func() (*pgslice.Plan, error) {
+				return pgslice.Prune(c, db, pgslice.PruneOptions{Table: table.Name, Keep: table.Keep, Drop: table.Drop})
+			})
+		}
+
+		if table.Analyze {
+			run("analyze", func() (*pgslice.Plan, error) {
+				return pgslice.Analyze(c, db, pgslice.AnalyzeOptions{Table: table.Name})
+			})
+		}
+	}
+	return ok
+}
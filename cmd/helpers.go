@@ -1,190 +1,153 @@
 package cmd
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"os"
-	"regexp"
-	"slices"
 	"strings"
-	"time"
 
-	"github.com/lib/pq"
+	"github.com/ankane/pgslice-go/pgslice"
 	"github.com/urfave/cli"
 )
 
-func CreateTable(name string) Table {
-	schema := "public"
-	if strings.Contains(name, ".") {
-		parts := strings.SplitN(name, ".", 2)
-		schema = parts[0]
-		name = parts[1]
+func Connection(ctx *cli.Context) (*sql.DB, error) {
+	config, err := ReadConfig(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return Table{Schema: schema, Name: name}
+	return Open(ctx, config)
 }
 
-func Connection(ctx *cli.Context) (*sql.DB, error) {
+// Open connects with --url, then the config, then PGSLICE_URL
+func Open(ctx *cli.Context, config *Config) (*sql.DB, error) {
 	url := ctx.String("url")
+	if url == "" {
+		var err error
+		url, err = config.ProfileURL(ctx.String("profile"))
+		if err != nil {
+			return nil, Abort(err.Error())
+		}
+	}
 	if url == "" {
 		url = os.Getenv("PGSLICE_URL")
 	}
 	return sql.Open("postgres", url)
 }
 
-func ServerVersionNum(db *sql.DB) (int, error) {
-	var num int
-	err := db.QueryRow("SHOW server_version_num").Scan(&num)
-	return num, err
-}
-
-func QuoteTable(table Table) string {
-	return strings.Join([]string{QuoteIdent(table.Schema), QuoteIdent(table.Name)}, ".")
-}
-
-func QuoteColumns(key []string) string {
-	quotedKey := make([]string, len(key))
-	for i, k := range key {
-		quotedKey[i] = QuoteIdent(k)
+// Tables returns the tables from --all or --table, or the TABLE argument
+func Tables(ctx *cli.Context) ([]TableConfig, error) {
+	if !ctx.Bool("all") && ctx.String("table") == "" {
+		return []TableConfig{{Name: ctx.Args().Get(0)}}, nil
 	}
-	return strings.Join(quotedKey, ", ")
-}
 
-func QuoteIdent(column string) string {
-	return pq.QuoteIdentifier(column)
-}
-
-func Contains(s []string, e string) bool {
-	return slices.Contains(s, e)
-}
-
-func RoundDate(t time.Time, period string) time.Time {
-	if period == "day" {
-		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
-	} else if period == "month" {
-		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if ctx.Bool("all") && ctx.String("table") != "" {
+		return nil, Abort("Can't use --all and --table")
 	}
-	return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
-}
 
-func NameFormat(period string) string {
-	if period == "day" {
-		return "20060102"
-	} else if period == "month" {
-		return "200601"
+	config, err := ReadConfig(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return "2006"
-}
 
-func SQLDate(time time.Time, cast string, addCast bool) string {
-	strFmt := "2006-01-02"
-	if cast == "timestamptz" {
-		strFmt = "2016-01-02 15:04:05 UTC"
+	if ctx.Bool("all") {
+		if len(config.Tables) == 0 {
+			return nil, Abort("No tables in config")
+		}
+		return config.Tables, nil
 	}
-	str := fmt.Sprintf("'%s'", time.Format(strFmt))
-	if addCast {
-		return fmt.Sprintf("%s::%s", str, cast)
+
+	table, err := config.Table(ctx.String("table"))
+	if err != nil {
+		return nil, Abort(err.Error())
 	}
-	return str
+	return []TableConfig{table}, nil
 }
 
-func AdvanceDate(date time.Time, period string, count int) time.Time {
-	if period == "day" {
-		return date.AddDate(0, 0, count)
-	} else if period == "month" {
-		return date.AddDate(0, count, 0)
-	} else {
-		return date.AddDate(count, 0, 0)
+// IntOption returns the flag if passed, then the config value if present,
+// then the flag default
+func IntOption(ctx *cli.Context, name string, value int) int {
+	if ctx.IsSet(name) || value == 0 {
+		return ctx.Int(name)
 	}
+	return value
 }
 
-func QuoteNoSchema(table Table) string {
-	return QuoteIdent(table.Name)
+func Int64Option(ctx *cli.Context, name string, value int64) int64 {
+	if ctx.IsSet(name) || value == 0 {
+		return ctx.Int64(name)
+	}
+	return value
 }
 
 func Abort(message string) error {
 	return cli.NewExitError(message, 1)
 }
 
-func RunQueries(db *sql.DB, queries []string, ctx *cli.Context) error {
-	queries = append([]string{"BEGIN;"}, queries...)
-	queries = append(queries, "COMMIT;")
-	return RunQueriesWithoutTransaction(db, queries, ctx)
-}
-
-func LogSQL(s string) {
-	fmt.Println(s)
-}
-
-func RunQueriesWithoutTransaction(db *sql.DB, queries []string, ctx *cli.Context) error {
-	for _, query := range queries {
-		err := RunQuery(db, query, ctx)
-		if err != nil {
-			return err
-		}
+// HandleError prints library errors without a timestamp like Abort
+func HandleError(err error) error {
+	var e *pgslice.Error
+	if errors.As(err, &e) {
+		return Abort(e.Message)
 	}
-	return nil
+	return err
 }
 
-func RunQuery(db *sql.DB, query string, ctx *cli.Context) error {
-	LogSQL(query)
-	LogSQL("")
-	if !ctx.Bool("dry-run") {
-		_, err := db.Exec(query)
+// WritePlan writes SQL for .sql files and JSON otherwise
+func WritePlan(plan *pgslice.Plan, path string) error {
+	var data []byte
+	if strings.HasSuffix(path, ".sql") {
+		data = []byte(plan.SQL())
+	} else {
+		var err error
+		data, err = plan.JSON()
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+	return os.WriteFile(path, data, 0644)
 }
 
-func MakeIndexDef(def string, table Table) string {
-	re1 := regexp.MustCompile(` ON \S+ USING `)
-	re2 := regexp.MustCompile(` INDEX .+ ON `)
-	def = re1.ReplaceAllString(def, fmt.Sprintf(" ON %s USING ", QuoteTable(table)))
-	def = re2.ReplaceAllString(def, " INDEX ON ")
-	return def + ";"
+func ExecuteOptions(ctx *cli.Context) pgslice.ExecuteOptions {
+	return pgslice.ExecuteOptions{DryRun: ctx.Bool("dry-run"), Log: os.Stdout}
 }
 
-func MakeFkDef(def string, table Table) string {
-	return "ALTER TABLE " + QuoteTable(table) + " ADD " + def + ";"
-}
-
-func PartitionDate(partition Table, nameFormat string) time.Time {
-	parts := strings.Split(partition.Name, "_")
-	day, _ := time.Parse(nameFormat, parts[len(parts)-1])
-	return day
-}
-
-func FetchSettings(db *sql.DB, originalTable Table, table Table) (string, string, string, bool, error) {
-	var field string
-	var period string
-	var cast string
-
-	triggerName := originalTable.TriggerName()
-	triggerComment, err := table.FetchTrigger(db, triggerName)
+// RunPlan connects, builds a plan, and executes it
+func RunPlan(ctx *cli.Context, build func(context.Context, *sql.DB) (*pgslice.Plan, error)) error {
+	db, err := Connection(ctx)
 	if err != nil {
-		return "", "", "", false, err
+		return err
 	}
+	defer db.Close()
 
-	comment := triggerComment
-	if comment == "" {
-		comment, err = table.FetchComment(db)
-		if err != nil {
-			return "", "", "", false, err
-		}
+	plan, err := build(context.Background(), db)
+	if err != nil {
+		return HandleError(err)
 	}
 
-	if comment != "" {
-		parts := make([]string, 3)
-		for i, part := range strings.Split(comment, ",") {
-			parts[i] = strings.Split(part, ":")[1]
-		}
-		field = parts[0]
-		period = parts[1]
-		cast = parts[2]
+	if ctx.String("plan-out") != "" {
+		return WritePlan(plan, ctx.String("plan-out"))
 	}
 
-	declarative := triggerComment == ""
+	return HandleError(plan.Execute(context.Background(), db, ExecuteOptions(ctx)))
+}
 
-	return period, field, cast, declarative, err
+// RunTablePlans builds a plan for each table and runs them as one plan
+func RunTablePlans(ctx *cli.Context, build func(context.Context, *sql.DB, TableConfig) (*pgslice.Plan, error)) error {
+	tables, err := Tables(ctx)
+	if err != nil {
+		return err
+	}
+
+	return RunPlan(ctx, func(c context.Context, db *sql.DB) (*pgslice.Plan, error) {
+		plan := &pgslice.Plan{}
+		for _, table := range tables {
+			tablePlan, err := build(c, db, table)
+			if err != nil {
+				return nil, err
+			}
+			plan.Steps = append(plan.Steps, tablePlan.Steps...)
+		}
+		return plan, nil
+	})
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"log"
 	"os"
+	"slices"
 
 	"github.com/urfave/cli"
 )
@@ -21,6 +22,42 @@ func RunApp(args []string) {
 				return Prep(ctx)
 			},
 			Flags: []cli.Flag{
+				cli.Int64Flag{
+					Name:  "interval",
+					Usage: "Partition an integer column by ranges of this size",
+				},
+				cli.StringFlag{
+					Name:  "strategy",
+					Usage: "Partitioning strategy (range, list, or hash)",
+				},
+				cli.BoolFlag{
+					Name:  "default-partition",
+					Usage: "Add a default partition for rows outside other partitions",
+				},
+				cli.StringFlag{
+					Name:  "time-zone",
+					Usage: "Time zone for partition boundaries (default UTC, not supported for hour)",
+				},
+				cli.StringFlag{
+					Name:  "cast",
+					Usage: "Units of an integer column (epoch or epoch_ms)",
+				},
+				cli.StringFlag{
+					Name:  "name-template",
+					Usage: "Template for partition names, like {table}_p{YYYY}_{MM}",
+				},
+				cli.StringFlag{
+					Name:  "partition-schema",
+					Usage: "Schema for partitions (default is the schema of the table)",
+				},
+				cli.StringFlag{
+					Name:  "sub-column",
+					Usage: "Hash partition each partition by a column",
+				},
+				cli.IntFlag{
+					Name:  "sub-modulus",
+					Usage: "Number of sub-partitions for each partition",
+				},
 				cli.BoolFlag{
 					Name:  "no-partition",
 					Usage: "Don't partition the table",
@@ -52,6 +89,74 @@ func RunApp(args []string) {
 					Usage: "Number of future partitions to add",
 					Value: 0,
 				},
+				cli.IntFlag{
+					Name:  "modulus",
+					Usage: "Number of partitions to add for hash partitioning",
+				},
+				cli.StringFlag{
+					Name:  "values",
+					Usage: "Comma-separated values to add partitions for with list partitioning",
+				},
+				cli.BoolFlag{
+					Name:  "default-partition",
+					Usage: "Add a default partition for rows outside other partitions",
+				},
+			},
+		},
+		{
+			Name:  "prune",
+			Usage: "Detach or drop old partitions",
+			Action: func(ctx *cli.Context) error {
+				return Prune(ctx)
+			},
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "keep",
+					Usage: "Number of partitions to keep, including the current one",
+				},
+				cli.BoolFlag{
+					Name:  "detach",
+					Usage: "Detach old partitions (default)",
+				},
+				cli.BoolFlag{
+					Name:  "drop",
+					Usage: "Drop old partitions",
+				},
+			},
+		},
+		{
+			Name:  "rescue",
+			Usage: "Move rows from the default partition to new partitions",
+			Action: func(ctx *cli.Context) error {
+				return Rescue(ctx)
+			},
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "intermediate",
+					Usage: "Use intermediate table",
+				},
+			},
+		},
+		{
+			Name:      "attach",
+			Usage:     "Attach an existing table as a partition",
+			ArgsUsage: "TABLE CHILD",
+			Action: func(ctx *cli.Context) error {
+				return Attach(ctx)
+			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "Start of the range (inclusive)",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "End of the range (exclusive)",
+				},
+				cli.BoolFlag{
+					Name:  "intermediate",
+					Usage: "Attach to intermediate table",
+				},
 			},
 		},
 		{
@@ -90,6 +195,42 @@ func RunApp(args []string) {
 					Name:  "sleep",
 					Usage: "Seconds to sleep between batches",
 				},
+				cli.IntFlag{
+					Name:  "jobs",
+					Usage: "Number of batches to run in parallel",
+					Value: 1,
+				},
+				cli.BoolFlag{
+					Name:  "resume",
+					Usage: "Resume from the last checkpoint",
+				},
+				cli.BoolFlag{
+					Name:  "show-checkpoint",
+					Usage: "Show the last checkpoint",
+				},
+				cli.BoolFlag{
+					Name:  "reset-checkpoint",
+					Usage: "Reset the last checkpoint",
+				},
+				cli.DurationFlag{
+					Name:  "max-replica-lag",
+					Usage: "Pause while replicas are behind by more than this (like 10s)",
+				},
+				cli.StringSliceFlag{
+					Name:  "replica-url",
+					Usage: "Replica to check for lag, in addition to pg_stat_replication",
+				},
+				cli.Int64Flag{
+					Name:  "max-wal-rate",
+					Usage: "Pause while writing more than this many MB of WAL per second",
+				},
+			},
+		},
+		{
+			Name:  "sync",
+			Usage: "Copy changes from the original table to the intermediate table",
+			Action: func(ctx *cli.Context) error {
+				return Sync(ctx)
 			},
 		},
 		{
@@ -119,6 +260,172 @@ func RunApp(args []string) {
 				},
 			},
 		},
+		{
+			Name:      "split",
+			Usage:     "Replace partitions in a range with smaller partitions",
+			ArgsUsage: "TABLE",
+			Action: func(ctx *cli.Context) error {
+				return Split(ctx)
+			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "Start of the range (inclusive)",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "End of the range (exclusive)",
+				},
+				cli.StringFlag{
+					Name:  "period",
+					Usage: "Period of the new partitions",
+				},
+				cli.IntFlag{
+					Name:  "batch-size",
+					Usage: "Batch size",
+					Value: 10000,
+				},
+				cli.IntFlag{
+					Name:  "sleep",
+					Usage: "Seconds to sleep between batches",
+				},
+				cli.StringFlag{
+					Name:  "lock-timeout",
+					Value: "5s",
+					Usage: "Lock timeout",
+				},
+				cli.BoolFlag{
+					Name:  "drop",
+					Usage: "Drop the old partitions",
+				},
+			},
+		},
+		{
+			Name:      "merge",
+			Usage:     "Replace partitions in a range with larger partitions",
+			ArgsUsage: "TABLE",
+			Action: func(ctx *cli.Context) error {
+				return Merge(ctx)
+			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "Start of the range (inclusive)",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "End of the range (exclusive)",
+				},
+				cli.StringFlag{
+					Name:  "period",
+					Usage: "Period of the new partitions",
+				},
+				cli.IntFlag{
+					Name:  "batch-size",
+					Usage: "Batch size",
+					Value: 10000,
+				},
+				cli.IntFlag{
+					Name:  "sleep",
+					Usage: "Seconds to sleep between batches",
+				},
+				cli.StringFlag{
+					Name:  "lock-timeout",
+					Value: "5s",
+					Usage: "Lock timeout",
+				},
+				cli.BoolFlag{
+					Name:  "drop",
+					Usage: "Drop the old partitions",
+				},
+			},
+		},
+		{
+			Name:  "upgrade",
+			Usage: "Convert trigger-based partitioning to declarative partitioning",
+			Action: func(ctx *cli.Context) error {
+				return Upgrade(ctx)
+			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "lock-timeout",
+					Value: "5s",
+					Usage: "Lock timeout",
+				},
+			},
+		},
+		{
+			Name:  "unpartition",
+			Usage: "Replace a partitioned table with a regular table",
+			Action: func(ctx *cli.Context) error {
+				return Unpartition(ctx)
+			},
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "batch-size",
+					Usage: "Batch size",
+					Value: 10000,
+				},
+				cli.IntFlag{
+					Name:  "sleep",
+					Usage: "Seconds to sleep between batches",
+				},
+				cli.StringFlag{
+					Name:  "lock-timeout",
+					Value: "5s",
+					Usage: "Lock timeout",
+				},
+			},
+		},
+		{
+			Name:  "daemon",
+			Usage: "Add partitions, prune, and analyze tables on a schedule",
+			Action: func(ctx *cli.Context) error {
+				return Daemon(ctx)
+			},
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "once",
+					Usage: "Run once and exit",
+				},
+			},
+		},
+		{
+			Name:  "status",
+			Usage: "Show the status of a table",
+			Action: func(ctx *cli.Context) error {
+				return Status(ctx)
+			},
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format",
+					Value: "text",
+					Usage: "Output format (text or json)",
+				},
+			},
+		},
+		{
+			Name:      "settings",
+			Usage:     "Show or update the settings of a table",
+			ArgsUsage: "TABLE",
+			Action: func(ctx *cli.Context) error {
+				return Settings(ctx)
+			},
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "set",
+					Usage: "Update a setting (key=value)",
+				},
+			},
+		},
+		{
+			Name:      "apply",
+			Usage:     "Run a plan saved with --plan-out",
+			ArgsUsage: "PLAN",
+			Action: func(ctx *cli.Context) error {
+				return Apply(ctx)
+			},
+		},
 		{
 			Name:  "unprep",
 			Usage: "Undo prep",
@@ -144,10 +451,47 @@ func RunApp(args []string) {
 			Name:  "dry-run",
 			Usage: "Print statements without executing",
 		},
+		cli.StringFlag{
+			Name:  "config",
+			Value: "pgslice.yml",
+			Usage: "Config file",
+		},
+		cli.StringFlag{
+			Name:  "profile",
+			Usage: "Connection profile from the config file",
+		},
+	}
+
+	// commands that take a table
+	tableCommands := []string{"prep", "add_partitions", "prune", "rescue", "fill", "sync", "analyze", "swap", "upgrade", "unpartition", "status", "settings", "unprep", "unswap"}
+	tableFlags := []cli.Flag{
+		cli.BoolFlag{
+			Name:  "all",
+			Usage: "Use all tables in the config file",
+		},
+		cli.StringFlag{
+			Name:  "table",
+			Usage: "Use a table in the config file",
+		},
+	}
+
+	// commands that run a plan
+	planCommands := []string{"prep", "add_partitions", "prune", "rescue", "attach", "sync", "analyze", "swap", "upgrade", "settings", "unprep", "unswap"}
+	planFlags := []cli.Flag{
+		cli.StringFlag{
+			Name:  "plan-out",
+			Usage: "Save the plan to a file (.json or .sql) instead of running it",
+		},
 	}
 
 	for i, command := range app.Commands {
 		app.Commands[i].Flags = append(command.Flags, sharedFlags...)
+		if slices.Contains(tableCommands, command.Name) {
+			app.Commands[i].Flags = append(app.Commands[i].Flags, tableFlags...)
+		}
+		if slices.Contains(planCommands, command.Name) {
+			app.Commands[i].Flags = append(app.Commands[i].Flags, planFlags...)
+		}
 	}
 
 	err := app.Run(args)
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -22,8 +24,17 @@ func TestMain(m *testing.M) {
   DROP TABLE IF EXISTS "Posts_intermediate" CASCADE;
   DROP TABLE IF EXISTS "Posts" CASCADE;
   DROP TABLE IF EXISTS "Posts_retired" CASCADE;
+  DROP TABLE IF EXISTS "Posts_original" CASCADE;
   DROP FUNCTION IF EXISTS "Posts_insert_trigger"();
   DROP TABLE IF EXISTS "Users" CASCADE;
+  DROP TABLE IF EXISTS "Comments_intermediate" CASCADE;
+  DROP TABLE IF EXISTS "Comments" CASCADE;
+  DROP TABLE IF EXISTS "Posts_2000" CASCADE;
+  DROP TABLE IF EXISTS "Posts_2001" CASCADE;
+  DROP TABLE IF EXISTS "Posts_2002" CASCADE;
+  DROP TABLE IF EXISTS pgslice_checkpoints;
+  DROP SCHEMA IF EXISTS archive CASCADE;
+  CREATE SCHEMA archive;
   CREATE TABLE "Users" (
     "Id" SERIAL PRIMARY KEY
   );
@@ -31,10 +42,23 @@ func TestMain(m *testing.M) {
     "Id" SERIAL PRIMARY KEY,
     "UserId" INTEGER,
     "createdAt" timestamp,
+    "createdAtMs" BIGINT,
     CONSTRAINT "foreign_key_1" FOREIGN KEY ("UserId") REFERENCES "Users"("Id")
   );
   CREATE INDEX ON "Posts" ("createdAt");
-  INSERT INTO "Posts" ("createdAt") SELECT NOW() FROM generate_series(1, 10000) n;
+  INSERT INTO "Posts" ("createdAt", "createdAtMs") SELECT NOW(), (extract(epoch FROM NOW()) * 1000)::bigint FROM generate_series(1, 10000) n;
+  CREATE TABLE "Comments" (
+    "PostId" INTEGER,
+    "Key" TEXT,
+    "createdAt" timestamp,
+    PRIMARY KEY ("PostId", "Key")
+  );
+  INSERT INTO "Comments" ("PostId", "Key", "createdAt") SELECT n % 100, md5(n::text), NOW() FROM generate_series(1, 10000) n;
+  CREATE TABLE "Posts_2000" (LIKE "Posts");
+  INSERT INTO "Posts_2000" ("Id", "createdAt") SELECT n, '2000-01-01'::timestamp + n * interval '1 hour' FROM generate_series(1, 1000) n;
+  CREATE TABLE "Posts_2001" (LIKE "Posts");
+  INSERT INTO "Posts_2001" ("Id", "createdAt") SELECT n, '2001-01-01'::timestamp + n * interval '1 hour' FROM generate_series(1, 1000) n;
+  CREATE TABLE "Posts_2002" (LIKE "Posts");
   `)
 	if err != nil {
 		log.Fatal(err)
@@ -48,10 +72,18 @@ func TestDay(t *testing.T) {
 	AssertPeriod(t, "day", false)
 }
 
+func TestWeek(t *testing.T) {
+	AssertPeriod(t, "week", false)
+}
+
 func TestMonth(t *testing.T) {
 	AssertPeriod(t, "month", false)
 }
 
+func TestQuarter(t *testing.T) {
+	AssertPeriod(t, "quarter", false)
+}
+
 func TestYear(t *testing.T) {
 	AssertPeriod(t, "year", false)
 }
@@ -64,6 +96,267 @@ func TestNoPartition(t *testing.T) {
 	RunCommand("unprep Posts")
 }
 
+func TestInterval(t *testing.T) {
+	RunCommand("prep Posts Id --interval 1000")
+	RunCommand("add_partitions Posts --intermediate --past 10 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("status Posts")
+	RunCommand("swap Posts")
+	RunCommand("prune Posts --keep 5 --drop")
+	RunCommand("unswap Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestDefaultPartition(t *testing.T) {
+	AssertDefaultPartition(t, "")
+}
+
+func TestDefaultPartitionTriggerBased(t *testing.T) {
+	AssertDefaultPartition(t, " --trigger-based")
+}
+
+func TestPruneDefaultPartition(t *testing.T) {
+	RunCommand("prep Posts Id --interval 1000 --default-partition")
+	RunCommand("add_partitions Posts --intermediate --past 10 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("swap Posts")
+	RunCommand("prune Posts --keep 5 --drop")
+	RunCommand("unswap Posts")
+	RunCommand("unprep Posts")
+}
+
+func AssertDefaultPartition(t *testing.T, options string) {
+	RunCommand("prep Posts Id --interval 1000 --default-partition" + options)
+	RunCommand("add_partitions Posts --intermediate")
+	RunCommand("fill Posts")
+	RunCommand("rescue Posts --intermediate")
+	RunCommand("rescue Posts --intermediate")
+	RunCommand("status Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestTimeZone(t *testing.T) {
+	RunCommand("prep Posts createdAt day --time-zone America/New_York")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("status Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestEpoch(t *testing.T) {
+	RunCommand("prep Posts createdAtMs day --trigger-based")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("status Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestCast(t *testing.T) {
+	RunCommand("prep Posts createdAtMs day --cast epoch_ms")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestNameTemplate(t *testing.T) {
+	RunCommand("prep Posts createdAt month --name-template {table}_p{YYYY}_{MM} --partition-schema archive")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("status Posts")
+	RunCommand("swap Posts")
+	RunCommand("add_partitions Posts --future 2")
+	RunCommand("prune Posts --keep 1 --drop")
+	RunCommand("unswap Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestAttach(t *testing.T) {
+	AssertAttach(t, "Posts_2000 --from 2000-01-01 --to 2001-01-01", "")
+}
+
+func TestAttachTriggerBased(t *testing.T) {
+	AssertAttach(t, "Posts_2001 --from 2001-01-01 --to 2002-01-01", " --trigger-based")
+}
+
+// unprep drops the child, so each test uses a different one
+func AssertAttach(t *testing.T, child string, options string) {
+	RunCommand("prep Posts createdAt month" + options)
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand(fmt.Sprintf("attach Posts %s --intermediate", child))
+	RunCommand("status Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestAttachSync(t *testing.T) {
+	RunCommand("prep Posts createdAt month")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("sync Posts")
+	RunCommand("attach Posts Posts_2002 --from 2002-01-01 --to 2003-01-01 --intermediate")
+	// synced to the attached partition
+	RunSQL(t, `INSERT INTO "Posts" ("createdAt") VALUES ('2002-06-01')`)
+	RunCommand("fill Posts")
+	RunCommand("swap Posts")
+	RunCommand("unswap Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestSplit(t *testing.T) {
+	AssertSplit(t, "")
+}
+
+func TestSplitTriggerBased(t *testing.T) {
+	AssertSplit(t, " --trigger-based")
+}
+
+func AssertSplit(t *testing.T, options string) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	rangeStr := fmt.Sprintf("--from %s --to %s", from.Format("2006-01-02"), from.AddDate(0, 1, 0).Format("2006-01-02"))
+
+	RunCommand("prep Posts createdAt month" + options)
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("swap Posts")
+	RunCommand(fmt.Sprintf("split Posts %s --period day --drop", rangeStr))
+	RunCommand("status Posts")
+	RunCommand(fmt.Sprintf("merge Posts %s --period month --drop", rangeStr))
+	RunCommand("status Posts")
+	RunCommand("unswap Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestUpgrade(t *testing.T) {
+	RunCommand("prep Posts createdAt month --trigger-based --default-partition")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("swap Posts")
+	RunCommand("upgrade Posts --dry-run")
+	RunCommand("upgrade Posts")
+	RunCommand("add_partitions Posts --future 2")
+	RunCommand("status Posts")
+	RunCommand("unswap Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestStatusPartitioned(t *testing.T) {
+	RunCommand("prep Posts createdAt month")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("swap Posts")
+	// like the retired table was dropped
+	RunSQL(t, `ALTER TABLE "Posts_retired" RENAME TO "Posts_original"`)
+	RunCommand("status Posts")
+	RunSQL(t, `ALTER TABLE "Posts_original" RENAME TO "Posts_retired"`)
+	RunCommand("unswap Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestUnpartition(t *testing.T) {
+	RunCommand("prep Posts createdAt month")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("swap Posts")
+	// the partitioned table becomes the retired table
+	RunSQL(t, `ALTER TABLE "Posts_retired" RENAME TO "Posts_original"`)
+	RunCommand("unpartition Posts --dry-run")
+	RunCommand("unpartition Posts")
+	RunCommand("status Posts")
+	RunSQL(t, `DROP TABLE "Posts_retired" CASCADE; DROP TABLE "Posts_original"`)
+}
+
+func TestSettings(t *testing.T) {
+	RunCommand("prep Posts createdAt day")
+	RunCommand("settings Posts")
+	RunCommand("settings Posts --set time_zone=America/New_York --set default=true")
+	RunCommand("settings Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestHash(t *testing.T) {
+	RunCommand("prep Comments PostId --strategy hash")
+	RunCommand("add_partitions Comments --intermediate --modulus 4")
+	RunCommand("fill Comments")
+	RunCommand("status Comments")
+	RunCommand("unprep Comments")
+}
+
+func TestList(t *testing.T) {
+	RunCommand("prep Comments PostId --strategy list")
+	RunCommand("add_partitions Comments --intermediate --values 1,2,3")
+	RunCommand("status Comments")
+	RunCommand("unprep Comments")
+}
+
+func TestSubpartition(t *testing.T) {
+	RunCommand("prep Posts createdAt month --sub-column UserId --sub-modulus 2")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("fill Posts")
+	RunCommand("status Posts")
+	RunCommand("analyze Posts")
+	RunCommand("swap Posts")
+	RunCommand("add_partitions Posts --future 2")
+	RunCommand("unswap Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	RunCommand("prep Posts --no-partition")
+	RunCommand(fmt.Sprintf("swap Posts --plan-out %s/swap.sql", dir))
+	RunCommand(fmt.Sprintf("swap Posts --plan-out %s/swap.json", dir))
+	RunCommand(fmt.Sprintf("apply %s/swap.json", dir))
+	RunCommand("unswap Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestSync(t *testing.T) {
+	RunCommand("prep Posts createdAt day")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("sync Posts")
+	RunCommand("fill Posts")
+	RunCommand("swap Posts")
+	RunCommand("unswap Posts")
+	RunCommand("unprep Posts")
+}
+
+func TestJobs(t *testing.T) {
+	RunCommand("prep Posts --no-partition")
+	RunCommand("fill Posts --batch-size 1000 --jobs 4")
+	RunCommand("unprep Posts")
+}
+
+func TestResume(t *testing.T) {
+	RunCommand("prep Posts --no-partition")
+	RunCommand("fill Posts --batch-size 1000")
+	RunCommand("fill Posts --show-checkpoint")
+	RunCommand("fill Posts --resume")
+	RunCommand("fill Posts --reset-checkpoint")
+	RunCommand("unprep Posts")
+}
+
+func TestMaxReplicaLag(t *testing.T) {
+	RunCommand("prep Posts createdAt day")
+	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
+	RunCommand("fill Posts --batch-size 1000 --max-replica-lag 10s --replica-url postgres://localhost/pgslice_test?sslmode=disable --max-wal-rate 1000")
+	RunCommand("unprep Posts")
+}
+
+func TestCompositeKey(t *testing.T) {
+	RunCommand("prep Comments --no-partition")
+	RunCommand("fill Comments --batch-size 1000")
+	RunCommand("fill Comments --batch-size 1000 --jobs 4 --where \"PostId\"<50")
+	RunCommand("unprep Comments")
+}
+
+func TestConfig(t *testing.T) {
+	config := WriteConfig(t, "tables:\n  - name: Posts\n    column: createdAt\n    period: day\n    past: 1\n    future: 1\n    batch_size: 1000\n    where: '\"createdAt\" IS NOT NULL'\n")
+	RunCommand(fmt.Sprintf("prep --table Posts --config %s", config))
+	RunCommand(fmt.Sprintf("add_partitions --table Posts --intermediate --config %s", config))
+	RunCommand(fmt.Sprintf("fill --all --config %s", config))
+	RunCommand(fmt.Sprintf("status --all --config %s", config))
+	RunCommand(fmt.Sprintf("unprep --all --config %s", config))
+}
+
 func TestTriggerBased(t *testing.T) {
 	AssertPeriod(t, "day", true)
 }
@@ -76,14 +369,40 @@ func AssertPeriod(t *testing.T, period string, triggerBased bool) {
 	RunCommand(fmt.Sprintf("prep Posts createdAt %s%s", period, triggerStr))
 	RunCommand("add_partitions Posts --intermediate --past 1 --future 1")
 	RunCommand("fill Posts")
+	RunCommand("status Posts")
 	RunCommand("analyze Posts")
 	RunCommand("swap Posts")
 	RunCommand("fill Posts --swapped")
 	RunCommand("add_partitions Posts --future 3")
+	RunCommand("prune Posts --keep 1 --drop")
+	RunCommand(fmt.Sprintf("daemon --once --config %s", WriteConfig(t, "tables:\n  - name: Posts\n    future: 3\n    analyze: true\n")))
+	RunCommand("status Posts --format json")
 	RunCommand("unswap Posts")
 	RunCommand("unprep Posts")
 }
 
+func WriteConfig(t *testing.T, config string) string {
+	path := filepath.Join(t.TempDir(), "pgslice.yml")
+	err := os.WriteFile(path, []byte(config), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func RunSQL(t *testing.T, query string) {
+	db, err := sql.Open("postgres", "postgres://localhost/pgslice_test?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func RunCommand(command string) {
 	fmt.Printf("pgslice %s\n", command)
 	fmt.Println("")
@@ -5,6 +5,7 @@ go 1.26
 require (
 	github.com/lib/pq v1.10.9
 	github.com/urfave/cli v1.22.17
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,348 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type AddPartitionsOptions struct {
+	Table            string
+	Intermediate     bool
+	Past             int
+	Future           int
+	Modulus          int
+	Values           []string
+	DefaultPartition bool
+}
+
+// partitionDef is a partition to create with its bound spec for
+// declarative partitioning or CHECK constraint for inheritance
+type partitionDef struct {
+	table  Table
+	values string
+	check  string
+	start  Bound
+	end    Bound
+}
+
+func rangePartitionDef(originalTable Table, settings Settings, start Bound) partitionDef {
+	end := settings.Advance(start, 1)
+
+	partition := settings.PartitionTable(originalTable, start)
+	values := fmt.Sprintf("FOR VALUES FROM (%s) TO (%s)", settings.SQL(start, false), settings.SQL(end, false))
+	return partitionDef{table: partition, values: values, check: rangeCheck(settings, start, end), start: start, end: end}
+}
+
+// overlaps returns true if a partition def overlaps an existing range
+func overlaps(ranges []PartitionRange, def partitionDef) bool {
+	for _, r := range ranges {
+		if r.Start.Before(def.end) && def.start.Before(r.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// partitionCreator adds steps to create partitions with the
+// primary key, indexes, and foreign keys of existing tables
+type partitionCreator struct {
+	table      Table
+	settings   Settings
+	primaryKey []string
+	indexDefs  []string
+	fkDefs     []string
+}
+
+func newPartitionCreator(ctx context.Context, db *sql.DB, originalTable Table, table Table, settings Settings) (*partitionCreator, error) {
+	declarative := settings.Declarative
+
+	var schemaTable Table
+	if !declarative {
+		schemaTable = table
+	} else if table != originalTable {
+		schemaTable = originalTable
+	} else {
+		partitions, err := originalTable.LeafPartitions(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		if len(partitions) == 0 {
+			return nil, abort("No partitions")
+		}
+		schemaTable = partitions[len(partitions)-1]
+	}
+
+	// indexes automatically propagate in Postgres 11+
+	indexDefs := []string{}
+	if !declarative {
+		serverVersionNum, err := fetchServerVersionNum(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		if serverVersionNum < 110000 {
+			indexDefs, err = schemaTable.IndexDefs(ctx, db)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	fkDefs, err := schemaTable.ForeignKeys(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	primaryKey, err := schemaTable.PrimaryKey(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &partitionCreator{table: table, settings: settings, primaryKey: primaryKey, indexDefs: indexDefs, fkDefs: fkDefs}, nil
+}
+
+func (c *partitionCreator) add(plan *Plan, def partitionDef) {
+	table := c.table
+	settings := c.settings
+	partition := def.table
+
+	// rows are stored in sub-partitions when sub-partitioning
+	leaves := []Table{partition}
+
+	if settings.Declarative && settings.Subpartitioned() {
+		plan.Add("Create partition", fmt.Sprintf("CREATE TABLE %s PARTITION OF %s %s PARTITION BY HASH (%s);", quoteTable(partition), quoteTable(table), def.values, quoteIdent(settings.SubColumn)), newLock(AccessExclusive, table))
+
+		leaves = []Table{}
+		for remainder := 0; remainder < settings.SubModulus; remainder++ {
+			subpartition := Table{Schema: partition.Schema, Name: fmt.Sprintf("%s_%d", partition.Name, remainder)}
+			plan.Add("Create sub-partition", fmt.Sprintf("CREATE TABLE %s PARTITION OF %s FOR VALUES WITH (MODULUS %d, REMAINDER %d);", quoteTable(subpartition), quoteTable(partition), settings.SubModulus, remainder), newLock(AccessExclusive, partition))
+			leaves = append(leaves, subpartition)
+		}
+	} else if settings.Declarative {
+		plan.Add("Create partition", fmt.Sprintf("CREATE TABLE %s PARTITION OF %s %s;", quoteTable(partition), quoteTable(table), def.values), newLock(AccessExclusive, table))
+	} else if def.check == "" {
+		plan.Add("Create partition", fmt.Sprintf(`CREATE TABLE %s
+    INHERITS (%s);`, quoteTable(partition), quoteTable(table)), newLock(ShareUpdateExclusive, table))
+	} else {
+		plan.Add("Create partition", fmt.Sprintf(`CREATE TABLE %s
+    (CHECK (%s))
+    INHERITS (%s);`, quoteTable(partition), def.check, quoteTable(table)), newLock(ShareUpdateExclusive, table))
+	}
+
+	for _, leaf := range leaves {
+		if len(c.primaryKey) > 0 {
+			plan.Add("Add primary key", fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", quoteTable(leaf), quoteColumns(c.primaryKey)), newLock(AccessExclusive, leaf))
+		}
+
+		for _, def := range c.indexDefs {
+			plan.Add("Copy index", makeIndexDef(def, leaf), newLock(Share, leaf))
+		}
+
+		for _, def := range c.fkDefs {
+			plan.Add("Copy foreign key", makeFkDef(def, leaf), makeFkLocks(def, leaf)...)
+		}
+	}
+}
+
+func AddPartitions(ctx context.Context, db *sql.DB, opts AddPartitionsOptions) (*Plan, error) {
+	originalTable := createTable(opts.Table)
+
+	table := originalTable
+	if opts.Intermediate {
+		table = table.IntermediateTable()
+	}
+	triggerName := originalTable.TriggerName()
+
+	exists, err := table.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	future := opts.Future
+	past := opts.Past
+
+	settings, err := fetchSettings(ctx, db, originalTable, table)
+	if err != nil {
+		return nil, err
+	}
+	declarative := settings.Declarative
+
+	if !settings.Partitioned() {
+		message := fmt.Sprintf("No settings found: %s", table.FullName())
+		if !opts.Intermediate {
+			message = message + "\nDid you mean to use --intermediate?"
+		}
+		return nil, abort(message)
+	}
+
+	switch settings.Strategy {
+	case "hash":
+		if opts.Modulus < 1 {
+			return nil, abort("Usage: pgslice add_partitions TABLE --modulus N")
+		}
+	case "list":
+		if len(opts.Values) == 0 {
+			return nil, abort("Usage: pgslice add_partitions TABLE --values A,B,C")
+		}
+	default:
+		if opts.Modulus != 0 || len(opts.Values) > 0 {
+			return nil, abort("--modulus and --values require --strategy hash or list")
+		}
+	}
+
+	// Postgres only checks a new hash partition against existing ones as
+	// it's created, so check up front instead of failing partway
+	if declarative && (settings.Strategy == "hash" || settings.SubColumn != "") {
+		modulus, option := opts.Modulus, "--modulus"
+		if settings.SubColumn != "" {
+			modulus, option = settings.SubModulus, "sub_modulus"
+		}
+		moduli, err := table.HashModuli(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		for _, existing := range moduli {
+			if existing != modulus {
+				return nil, abort(fmt.Sprintf("%s %d doesn't match existing partitions with modulus %d", option, modulus, existing))
+			}
+		}
+	}
+
+	if opts.DefaultPartition && settings.Strategy == "hash" {
+		return nil, abort("Can't use --default-partition with --strategy hash")
+	}
+
+	plan := &Plan{}
+
+	if opts.DefaultPartition && !settings.Default {
+		if declarative {
+			serverVersionNum, err := fetchServerVersionNum(ctx, db)
+			if err != nil {
+				return nil, err
+			}
+			if serverVersionNum < 110000 {
+				return nil, abort("--default-partition requires Postgres 11+")
+			}
+		}
+
+		settings.Default = true
+		addSaveSettings(plan, table, triggerName, settings)
+	}
+
+	creator, err := newPartitionCreator(ctx, db, originalTable, table, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var current Bound
+	defs := []partitionDef{}
+	switch settings.Strategy {
+	case "hash":
+		for remainder := 0; remainder < opts.Modulus; remainder++ {
+			partition := Table{Schema: originalTable.Schema, Name: fmt.Sprintf("%s_%d", originalTable.Name, remainder)}
+			defs = append(defs, partitionDef{table: partition, values: fmt.Sprintf("FOR VALUES WITH (MODULUS %d, REMAINDER %d)", opts.Modulus, remainder)})
+		}
+	case "list":
+		for _, value := range opts.Values {
+			partition := Table{Schema: originalTable.Schema, Name: fmt.Sprintf("%s_%s", originalTable.Name, value)}
+			defs = append(defs, partitionDef{table: partition, values: fmt.Sprintf("FOR VALUES IN (%s)", pq.QuoteLiteral(value))})
+		}
+	default:
+		// the original table has the data before and after swap
+		current, err = settings.Current(ctx, db, originalTable)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := past * -1; i <= future; i++ {
+			defs = append(defs, rangePartitionDef(originalTable, settings, settings.Advance(current, i)))
+		}
+	}
+
+	var existingRanges []PartitionRange
+	if settings.Ranged() {
+		existingRanges, err = table.PartitionBounds(ctx, db, settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	addedRanges := []PartitionRange{}
+
+	for _, def := range defs {
+		partition := def.table
+		// partitions may have other names or ranges
+		if settings.Ranged() && overlaps(existingRanges, def) {
+			continue
+		}
+		exists, err := partition.Exists(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			continue
+		}
+		addedRanges = append(addedRanges, PartitionRange{Table: partition, Start: def.start, End: def.end})
+
+		creator.add(plan, def)
+	}
+
+	// add after other partitions so they don't need to check it for rows
+	if settings.Default {
+		defaultPartition := originalTable.DefaultPartition()
+		exists, err := defaultPartition.Exists(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			creator.add(plan, partitionDef{table: defaultPartition, values: "DEFAULT"})
+		}
+	}
+
+	if !declarative {
+		// update trigger based on existing partitions
+		partitions := append(existingRanges, addedRanges...)
+
+		if len(partitions) > 0 {
+			plan.Add("Update insert trigger function", makeTriggerDef(triggerName, partitions, settings, current, originalTable.DefaultPartition()))
+		}
+	}
+
+	// the sync trigger copies rows in the same range as fill
+	if opts.Intermediate && !plan.Empty() {
+		err := addUpdateSyncFunction(ctx, db, plan, originalTable, table, settings, append(existingRanges, addedRanges...))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+func addUpdateSyncFunction(ctx context.Context, db *sql.DB, plan *Plan, originalTable Table, table Table, settings Settings, partitions []PartitionRange) error {
+	syncing, err := originalTable.TriggerExists(ctx, db, originalTable.SyncTriggerName())
+	if err != nil {
+		return err
+	}
+	if !syncing {
+		return nil
+	}
+
+	primaryKey, err := originalTable.PrimaryKey(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	columns, err := originalTable.Columns(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	plan.Add("Update sync trigger function", syncFunctionDef(originalTable.SyncTriggerName(), intermediateSyncTargets(table, settings, partitions), settings, primaryKey, columns))
+	return nil
+}
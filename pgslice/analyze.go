@@ -0,0 +1,35 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type AnalyzeOptions struct {
+	Table   string
+	Swapped bool
+}
+
+func Analyze(ctx context.Context, db *sql.DB, opts AnalyzeOptions) (*Plan, error) {
+	table := createTable(opts.Table)
+
+	parentTable := table
+	if opts.Swapped {
+		parentTable = table.IntermediateTable()
+	}
+
+	// include sub-partitions
+	partitions, err := table.AllPartitions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	analyzeList := append(partitions, parentTable)
+
+	plan := &Plan{}
+	for _, t := range analyzeList {
+		plan.AddWithoutTransaction("Analyze table", fmt.Sprintf("ANALYZE VERBOSE %s;", quoteTable(t)), newLock(ShareUpdateExclusive, t))
+	}
+
+	return plan, nil
+}
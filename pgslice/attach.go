@@ -0,0 +1,175 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type AttachOptions struct {
+	Table        string
+	Child        string
+	From         string
+	To           string
+	Intermediate bool
+}
+
+// Attach adds an existing table as a partition. A CHECK constraint is
+// added NOT VALID and validated before attaching, so Postgres doesn't
+// scan the table while holding an ACCESS EXCLUSIVE lock.
+func Attach(ctx context.Context, db *sql.DB, opts AttachOptions) (*Plan, error) {
+	originalTable := createTable(opts.Table)
+
+	table := originalTable
+	if opts.Intermediate {
+		table = table.IntermediateTable()
+	}
+	triggerName := originalTable.TriggerName()
+	child := createTable(opts.Child)
+
+	if opts.Child == "" || opts.From == "" || opts.To == "" {
+		return nil, abort("Usage: pgslice attach TABLE CHILD --from FROM --to TO")
+	}
+
+	for _, t := range []Table{table, child} {
+		exists, err := t.Exists(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, abort(fmt.Sprintf("Table not found: %s", t.FullName()))
+		}
+	}
+
+	settings, err := fetchSettings(ctx, db, originalTable, table)
+	if err != nil {
+		return nil, err
+	}
+	declarative := settings.Declarative
+
+	if !settings.Partitioned() {
+		message := fmt.Sprintf("No settings found: %s", table.FullName())
+		if !opts.Intermediate {
+			message = message + "\nDid you mean to use --intermediate?"
+		}
+		return nil, abort(message)
+	}
+
+	if !settings.Ranged() {
+		return nil, abort(fmt.Sprintf("Can't attach to %s partitions", settings.Strategy))
+	}
+
+	start, err := settings.ParseBound(opts.From)
+	if err != nil {
+		return nil, abort("Invalid from: " + opts.From)
+	}
+	end, err := settings.ParseBound(opts.To)
+	if err != nil {
+		return nil, abort("Invalid to: " + opts.To)
+	}
+	if !start.Before(end) {
+		return nil, abort("From must be before to")
+	}
+
+	err = checkColumns(ctx, db, table, child)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := table.Partitions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(children, child) {
+		return nil, abort(fmt.Sprintf("Already a partition: %s", child.FullName()))
+	}
+
+	partitions, err := table.PartitionBounds(ctx, db, settings)
+	if err != nil {
+		return nil, err
+	}
+	for _, partition := range partitions {
+		if partition.Start.Before(end) && start.Before(partition.End) {
+			return nil, abort(fmt.Sprintf("Range overlaps partition: %s", partition.Table.FullName()))
+		}
+	}
+
+	constraintName := fmt.Sprintf("%s_%s_check", child.Name, settings.Column)
+	check := rangeCheck(settings, start, end)
+
+	plan := &Plan{}
+
+	// not in a transaction so the lock is released before validating
+	plan.AddWithoutTransaction("Add check constraint", fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s) NOT VALID;", quoteTable(child), quoteIdent(constraintName), check), newLock(AccessExclusive, child))
+	plan.AddWithoutTransaction("Validate check constraint", fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s;", quoteTable(child), quoteIdent(constraintName)), newLock(ShareUpdateExclusive, child))
+
+	partitions = append(partitions, PartitionRange{Table: child, Start: start, End: end})
+
+	if declarative {
+		// the constraint lets Postgres skip scanning the table
+		plan.Add("Attach partition", fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (%s) TO (%s);", quoteTable(table), quoteTable(child), settings.SQL(start, false), settings.SQL(end, false)), newLock(ShareUpdateExclusive, table), newLock(AccessExclusive, child))
+		// the partition bound replaces the constraint
+		plan.Add("Drop check constraint", fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", quoteTable(child), quoteIdent(constraintName)), newLock(AccessExclusive, child))
+	} else {
+		plan.Add("Inherit table", fmt.Sprintf("ALTER TABLE %s INHERIT %s;", quoteTable(child), quoteTable(table)), newLock(AccessExclusive, child), newLock(ShareUpdateExclusive, table))
+
+		current, err := settings.Current(ctx, db, originalTable)
+		if err != nil {
+			return nil, err
+		}
+		plan.Add("Update insert trigger function", makeTriggerDef(triggerName, partitions, settings, current, originalTable.DefaultPartition()))
+	}
+
+	// the sync trigger copies rows in the same range as fill
+	if opts.Intermediate {
+		err := addUpdateSyncFunction(ctx, db, plan, originalTable, table, settings, partitions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// rangeCheck is a CHECK constraint for a range. Like the partition
+// constraint, it excludes nulls, so Postgres can use it to attach the
+// table without scanning it.
+func rangeCheck(settings Settings, start Bound, end Bound) string {
+	field := quoteIdent(settings.Column)
+	return fmt.Sprintf("%s IS NOT NULL AND %s >= %s AND %s < %s", field, field, settings.SQL(start, true), field, settings.SQL(end, true))
+}
+
+// checkColumns returns an error unless a table has the same columns as its parent
+func checkColumns(ctx context.Context, db *sql.DB, parent Table, table Table) error {
+	parentColumns, err := parent.Columns(ctx, db)
+	if err != nil {
+		return err
+	}
+	columns, err := table.Columns(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	missing := []string{}
+	for _, column := range parentColumns {
+		if !contains(columns, column) {
+			missing = append(missing, column)
+		}
+	}
+	extra := []string{}
+	for _, column := range columns {
+		if !contains(parentColumns, column) {
+			extra = append(extra, column)
+		}
+	}
+
+	if len(missing) > 0 {
+		return abort(fmt.Sprintf("Missing columns in %s: %s", table.FullName(), strings.Join(missing, ", ")))
+	}
+	if len(extra) > 0 {
+		return abort(fmt.Sprintf("Extra columns in %s: %s", table.FullName(), strings.Join(extra, ", ")))
+	}
+	return nil
+}
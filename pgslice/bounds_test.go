@@ -0,0 +1,80 @@
+package pgslice
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+	}{
+		{"date", Settings{Column: "createdAt", Period: "day", Cast: "date"}},
+		{"timestamp", Settings{Column: "createdAt", Period: "day", Cast: "timestamp"}},
+		{"timestamptz", Settings{Column: "createdAt", Period: "day", Cast: "timestamptz"}},
+		{"timestamptz_time_zone", Settings{Column: "createdAt", Period: "day", Cast: "timestamptz", TimeZone: "America/New_York"}},
+		{"hour", Settings{Column: "createdAt", Period: "hour", Cast: "timestamptz"}},
+		{"epoch", Settings{Column: "createdAt", Period: "day", Cast: "epoch"}},
+		{"epoch_ms", Settings{Column: "createdAt", Period: "day", Cast: "epoch_ms"}},
+		{"interval", Settings{Column: "Id", Interval: 1000, Cast: "bigint"}},
+		{"name_template", Settings{Column: "createdAt", Period: "month", Cast: "date", NameTemplate: "{table}_p{YYYY}_{MM}", PartitionSchema: "archive"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := createTable("Posts")
+
+			var current Bound
+			if tt.settings.Numeric() {
+				current = tt.settings.Round(Bound{Value: 2500})
+			} else if tt.settings.Period == "hour" {
+				// spans the end of daylight saving time in America/New_York,
+				// where local hours repeat
+				current = tt.settings.Round(Bound{Time: time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC)})
+			} else {
+				// spans the start of daylight saving time
+				current = tt.settings.Round(Bound{Time: time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)})
+			}
+
+			var sb strings.Builder
+			partitions := []PartitionRange{}
+			for i := -1; i <= 1; i++ {
+				def := rangePartitionDef(table, tt.settings, tt.settings.Advance(current, i))
+				partitions = append(partitions, PartitionRange{Table: def.table, Start: def.start, End: def.end})
+				sb.WriteString("-- " + def.table.Name + "\n")
+				sb.WriteString(def.values + "\n")
+				sb.WriteString("CHECK (" + def.check + ")\n\n")
+			}
+			sb.WriteString(makeTriggerDef(table.TriggerName(), partitions, tt.settings, current, table.DefaultPartition()))
+			sb.WriteString("\n")
+
+			assertGolden(t, filepath.Join("testdata", "bounds", tt.name+".sql"), sb.String())
+		})
+	}
+}
+
+func assertGolden(t *testing.T, path string, actual string) {
+	t.Helper()
+
+	if *update {
+		err := os.WriteFile(path, []byte(actual), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != string(expected) {
+		t.Errorf("%s does not match\n\nexpected:\n%s\nactual:\n%s", path, expected, actual)
+	}
+}
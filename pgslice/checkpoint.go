@@ -0,0 +1,123 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Checkpoint struct {
+	Table       string
+	SourceTable string
+	LastKey     []string
+	BatchCount  int
+	StartedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func checkpointTable(table Table) Table {
+	return Table{Schema: table.Schema, Name: "pgslice_checkpoints"}
+}
+
+func createCheckpointTable(ctx context.Context, db *sql.DB, table Table) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  table_name text NOT NULL,
+  source_table text NOT NULL,
+  last_key jsonb NOT NULL,
+  batch_count integer NOT NULL,
+  started_at timestamptz NOT NULL,
+  updated_at timestamptz NOT NULL,
+  PRIMARY KEY (table_name, source_table)
+)`, quoteTable(checkpointTable(table)))
+	_, err := db.ExecContext(ctx, query)
+	return err
+}
+
+func fetchCheckpoint(ctx context.Context, db *sql.DB, destTable Table, sourceTable Table) (*Checkpoint, error) {
+	exists, err := checkpointTable(destTable).Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf("SELECT table_name, source_table, last_key, batch_count, started_at, updated_at FROM %s WHERE table_name = $1 AND source_table = $2", quoteTable(checkpointTable(destTable)))
+
+	var c Checkpoint
+	var lastKey []byte
+	err = db.QueryRowContext(ctx, query, destTable.FullName(), sourceTable.FullName()).Scan(&c.Table, &c.SourceTable, &lastKey, &c.BatchCount, &c.StartedAt, &c.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(lastKey, &c.LastKey)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func saveCheckpoint(ctx context.Context, tx *sql.Tx, destTable Table, c Checkpoint) error {
+	lastKey, err := json.Marshal(c.LastKey)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (table_name, source_table, last_key, batch_count, started_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (table_name, source_table) DO UPDATE SET
+  last_key = EXCLUDED.last_key,
+  batch_count = EXCLUDED.batch_count,
+  started_at = EXCLUDED.started_at,
+  updated_at = EXCLUDED.updated_at`, quoteTable(checkpointTable(destTable)))
+	_, err = tx.ExecContext(ctx, query, c.Table, c.SourceTable, string(lastKey), c.BatchCount, c.StartedAt)
+	return err
+}
+
+func resetCheckpoint(ctx context.Context, db *sql.DB, destTable Table, sourceTable Table) (*Plan, error) {
+	exists, err := checkpointTable(destTable).Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return &Plan{}, nil
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE table_name = %s AND source_table = %s;", quoteTable(checkpointTable(destTable)), pq.QuoteLiteral(destTable.FullName()), pq.QuoteLiteral(sourceTable.FullName()))
+	plan := &Plan{}
+	plan.AddWithoutTransaction("Reset checkpoint", query, newLock(RowExclusive, checkpointTable(destTable)))
+	return plan, nil
+}
+
+// runBatch runs a fill batch and saves the checkpoint in the same transaction
+func runBatch(ctx context.Context, db *sql.DB, query string, destTable Table, checkpoint Checkpoint, opts ExecuteOptions) error {
+	logSQL(opts.Log, query)
+	if opts.DryRun {
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	err = saveCheckpoint(ctx, tx, destTable, checkpoint)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,585 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type FillOptions struct {
+	Table       string
+	BatchSize   int
+	Swapped     bool
+	SourceTable string
+	DestTable   string
+	Start       int
+	Where       string
+	Sleep       time.Duration
+	Jobs        int
+	Resume      bool
+	DryRun      bool
+	Log         io.Writer
+
+	// pause while replicas are behind or the WAL rate
+	// (bytes per second) is too high
+	MaxReplicaLag time.Duration
+	ReplicaURLs   []string
+	MaxWALRate    int64
+}
+
+func (opts FillOptions) tables() (Table, Table) {
+	table := createTable(opts.Table)
+
+	var sourceTable Table
+	if opts.SourceTable != "" {
+		sourceTable = createTable(opts.SourceTable)
+	}
+	var destTable Table
+	if opts.DestTable != "" {
+		destTable = createTable(opts.DestTable)
+	}
+
+	if opts.Swapped {
+		if sourceTable == (Table{}) {
+			sourceTable = table.RetiredTable()
+		}
+		if destTable == (Table{}) {
+			destTable = table
+		}
+	} else {
+		if sourceTable == (Table{}) {
+			sourceTable = table
+		}
+		if destTable == (Table{}) {
+			destTable = table.IntermediateTable()
+		}
+	}
+	return sourceTable, destTable
+}
+
+// FillCheckpoint returns the last checkpoint, or nil if there isn't one
+func FillCheckpoint(ctx context.Context, db *sql.DB, opts FillOptions) (*Checkpoint, error) {
+	sourceTable, destTable := opts.tables()
+	return fetchCheckpoint(ctx, db, destTable, sourceTable)
+}
+
+func ResetFillCheckpoint(ctx context.Context, db *sql.DB, opts FillOptions) (*Plan, error) {
+	sourceTable, destTable := opts.tables()
+	return resetCheckpoint(ctx, db, destTable, sourceTable)
+}
+
+func Fill(ctx context.Context, db *sql.DB, opts FillOptions) error {
+	table := createTable(opts.Table)
+	swapped := opts.Swapped
+	sleep := opts.Sleep
+	sourceTable, destTable := opts.tables()
+
+	sourceExists, err := sourceTable.Exists(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !sourceExists {
+		return abort(fmt.Sprintf("Table not found: %s", sourceTable.FullName()))
+	}
+
+	destExists, err := destTable.Exists(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !destExists {
+		return abort(fmt.Sprintf("Table not found: %s", destTable.FullName()))
+	}
+
+	resume := opts.Resume
+	if resume && opts.Start > 0 {
+		return abort("Can't use --resume and --start")
+	}
+
+	var checkpoint *Checkpoint
+	if resume {
+		checkpoint, err = fetchCheckpoint(ctx, db, destTable, sourceTable)
+		if err != nil {
+			return err
+		}
+		if checkpoint == nil {
+			return abort(fmt.Sprintf("No checkpoint found: %s", destTable.FullName()))
+		}
+	}
+
+	settings, err := fetchSettings(ctx, db, table, destTable)
+	if err != nil {
+		return err
+	}
+	field := settings.Column
+
+	// only copy rows that fit in a partition
+	var rangeFilter string
+	starting, ending, err := partitionedRange(ctx, db, destTable, settings)
+	if err != nil {
+		return err
+	}
+	if starting != nil {
+		rangeFilter = fmt.Sprintf("%s >= %s AND %s < %s", quoteIdent(field), settings.SQL(*starting, true), quoteIdent(field), settings.SQL(*ending, true))
+	}
+
+	schemaTable := table
+	if settings.Partitioned() && settings.Declarative {
+		partitions, err := destTable.LeafPartitions(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		if len(partitions) == 0 {
+			return abort("No partitions")
+		}
+		schemaTable = partitions[len(partitions)-1]
+	}
+
+	primaryKey, err := schemaTable.PrimaryKey(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if len(primaryKey) == 0 {
+		return abort("No primary key")
+	}
+
+	keyType, err := schemaTable.ColumnDataType(ctx, db, primaryKey[0])
+	if err != nil {
+		return err
+	}
+	integerKey := len(primaryKey) == 1 && contains([]string{"smallint", "integer", "bigint"}, keyType)
+
+	if !integerKey && opts.Start > 0 {
+		return abort("--start requires an integer primary key")
+	}
+
+	columns, err := sourceTable.Columns(ctx, db)
+	if err != nil {
+		return err
+	}
+	fields := quoteColumns(columns)
+
+	filters := []string{}
+	if rangeFilter != "" {
+		filters = append(filters, rangeFilter)
+	}
+	if opts.Where != "" {
+		filters = append(filters, opts.Where)
+	}
+
+	// rows may already be copied by the sync trigger
+	syncing, err := sourceTable.TriggerExists(ctx, db, sourceTable.SyncTriggerName())
+	if err != nil {
+		return err
+	}
+	if syncing {
+		matchColumns := primaryKey
+		if field != "" && !contains(primaryKey, field) {
+			// allow partition pruning
+			matchColumns = append([]string{field}, primaryKey...)
+		}
+		matches := make([]string, len(matchColumns))
+		for i, k := range matchColumns {
+			matches[i] = fmt.Sprintf("%s.%s = %s.%s", quoteTable(destTable), quoteIdent(k), quoteTable(sourceTable), quoteIdent(k))
+		}
+		filters = append(filters, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s)", quoteTable(destTable), strings.Join(matches, " AND ")))
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize == 0 {
+		batchSize = 10000
+	}
+
+	jobs := opts.Jobs
+	if jobs == 0 {
+		jobs = 1
+	}
+	if jobs < 1 {
+		return abort("Invalid jobs: " + strconv.Itoa(jobs))
+	}
+
+	previousBatches := 0
+	startedAt := time.Now()
+	if checkpoint != nil {
+		previousBatches = checkpoint.BatchCount
+		startedAt = checkpoint.StartedAt
+	}
+
+	// while syncing, lock the rows so the sync trigger for an update or
+	// delete runs after the batch commits, and skip rows it already copied
+	var locking string
+	var onConflict string
+	if syncing {
+		locking = "\n    FOR SHARE"
+
+		// partitioned tables support ON CONFLICT in Postgres 11+
+		serverVersionNum, err := fetchServerVersionNum(ctx, db)
+		if err != nil {
+			return err
+		}
+		if serverVersionNum >= 110000 {
+			onConflict = "\n    ON CONFLICT DO NOTHING"
+		}
+	}
+
+	makeBatch := func(i int, comment string, conditions []string, lastKey []string) *fillBatch {
+		where := strings.Join(append(conditions, filters...), " AND ")
+
+		query := fmt.Sprintf(`/* %s */
+INSERT INTO %s (%s)
+    SELECT %s FROM %s
+    WHERE %s%s%s`, comment, quoteTable(destTable), fields, fields, quoteTable(sourceTable), where, locking, onConflict)
+
+		return &fillBatch{Query: query, Checkpoint: Checkpoint{Table: destTable.FullName(), SourceTable: sourceTable.FullName(), LastKey: lastKey, BatchCount: previousBatches + i, StartedAt: startedAt}}
+	}
+
+	var next func(i int) (*fillBatch, error)
+	if integerKey {
+		primaryKeyColumn := primaryKey[0]
+
+		maxSourceID, err := sourceTable.MaxID(ctx, db, primaryKeyColumn, "", -1)
+		if err != nil {
+			return err
+		}
+
+		var maxDestID int64
+		if checkpoint != nil {
+			if len(checkpoint.LastKey) != 1 {
+				return abort("Checkpoint doesn't match primary key")
+			}
+			maxDestID, err = strconv.ParseInt(checkpoint.LastKey[0], 10, 64)
+			if err != nil {
+				return abort("Checkpoint doesn't match primary key")
+			}
+		} else if opts.Start > 0 {
+			maxDestID = int64(opts.Start)
+		} else if swapped {
+			maxDestID, err = destTable.MaxID(ctx, db, primaryKeyColumn, opts.Where, maxSourceID)
+		} else if !syncing {
+			maxDestID, err = destTable.MaxID(ctx, db, primaryKeyColumn, opts.Where, -1)
+		}
+		if err != nil {
+			return err
+		}
+
+		if maxDestID == 0 && !swapped && checkpoint == nil {
+			conditions := []string{}
+			if rangeFilter != "" {
+				conditions = append(conditions, rangeFilter)
+			}
+			if opts.Where != "" {
+				conditions = append(conditions, opts.Where)
+			}
+			minSourceID, err := sourceTable.MinID(ctx, db, primaryKeyColumn, conditions)
+			if err != nil {
+				return err
+			}
+			maxDestID = minSourceID - 1
+		}
+
+		startingID := maxDestID
+		batchCount := int(math.Ceil(float64(maxSourceID-startingID) / float64(batchSize)))
+
+		next = func(i int) (*fillBatch, error) {
+			if i > batchCount {
+				return nil, nil
+			}
+
+			batchStartingID := startingID + int64((i-1)*batchSize)
+			batchEndingID := batchStartingID + int64(batchSize)
+			conditions := []string{fmt.Sprintf("%s > %d AND %s <= %d", quoteIdent(primaryKeyColumn), batchStartingID, quoteIdent(primaryKeyColumn), batchEndingID)}
+			lastID := min(batchEndingID, maxSourceID)
+			return makeBatch(i, fmt.Sprintf("%d of %d", i, batchCount), conditions, []string{strconv.FormatInt(lastID, 10)}), nil
+		}
+	} else {
+		// keyset pagination for non-integer and composite keys
+		// batch boundaries come from the source, so gaps don't matter
+		maxSourceKey, err := sourceTable.MaxKey(ctx, db, primaryKey, nil)
+		if err != nil {
+			return err
+		}
+
+		var lastKey []string
+		if checkpoint != nil {
+			if len(checkpoint.LastKey) != len(primaryKey) {
+				return abort("Checkpoint doesn't match primary key")
+			}
+			lastKey = checkpoint.LastKey
+		} else if maxSourceKey != nil && !syncing {
+			conditions := []string{}
+			if opts.Where != "" {
+				conditions = append(conditions, opts.Where)
+			}
+			if swapped {
+				conditions = append(conditions, keyCondition(primaryKey, "<=", maxSourceKey))
+			}
+			lastKey, err = destTable.MaxKey(ctx, db, primaryKey, conditions)
+			if err != nil {
+				return err
+			}
+		}
+
+		next = func(i int) (*fillBatch, error) {
+			if maxSourceKey == nil {
+				return nil, nil
+			}
+
+			conditions := []string{keyCondition(primaryKey, "<=", maxSourceKey)}
+			if lastKey != nil {
+				conditions = append(conditions, keyCondition(primaryKey, ">", lastKey))
+			}
+			conditions = append(conditions, filters...)
+
+			upperKey, err := sourceTable.NextKey(ctx, db, primaryKey, conditions, batchSize-1)
+			if err != nil {
+				return nil, err
+			}
+			if upperKey == nil {
+				firstKey, err := sourceTable.NextKey(ctx, db, primaryKey, conditions, 0)
+				if err != nil {
+					return nil, err
+				}
+				if firstKey == nil {
+					return nil, nil
+				}
+				upperKey = maxSourceKey
+			}
+
+			batchConditions := []string{}
+			if lastKey != nil {
+				batchConditions = append(batchConditions, keyCondition(primaryKey, ">", lastKey))
+			}
+			batchConditions = append(batchConditions, keyCondition(primaryKey, "<=", upperKey))
+
+			lastKey = upperKey
+			return makeBatch(i, fmt.Sprintf("batch %d", i), batchConditions, upperKey), nil
+		}
+	}
+
+	batch, err := next(1)
+	if err != nil {
+		return err
+	}
+	if batch == nil {
+		logSQL(opts.Log, "/* nothing to fill */")
+		return nil
+	}
+
+	var throttle *fillThrottle
+	if !opts.DryRun {
+		err = createCheckpointTable(ctx, db, destTable)
+		if err != nil {
+			return err
+		}
+
+		throttle, err = newFillThrottle(ctx, db, opts)
+		if err != nil {
+			return err
+		}
+		defer throttle.Close()
+	}
+
+	if jobs > 1 && !opts.DryRun {
+		return runBatchesInParallel(ctx, db, batch, next, jobs, sleep, throttle, destTable, opts.Log)
+	}
+
+	for i := 1; batch != nil; i++ {
+		err := runBatch(ctx, db, batch.Query, destTable, batch.Checkpoint, ExecuteOptions{DryRun: opts.DryRun, Log: opts.Log})
+		if err != nil {
+			return err
+		}
+
+		batch, err = next(i + 1)
+		if err != nil {
+			return err
+		}
+
+		if batch != nil {
+			if sleep > 0 {
+				time.Sleep(sleep)
+			}
+
+			err = throttle.Wait(ctx)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type fillBatch struct {
+	Query      string
+	Checkpoint Checkpoint
+}
+
+// runBatchesInParallel runs batches on multiple connections. Inserts run
+// concurrently, but each batch waits for the previous one before committing,
+// so output stays in order and the checkpoint never skips over a batch.
+// It uses up to jobs connections, plus one to find batches.
+func runBatchesInParallel(ctx context.Context, db *sql.DB, first *fillBatch, next func(int) (*fillBatch, error), jobs int, sleep time.Duration, throttle *fillThrottle, destTable Table, log io.Writer) error {
+	type indexedBatch struct {
+		index int
+		batch *fillBatch
+	}
+
+	order := newCommitOrder(1)
+	batches := make(chan indexedBatch)
+	var wg sync.WaitGroup
+
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				// keep draining after a failure so the sender doesn't block
+				if order.Err() != nil {
+					continue
+				}
+
+				err := runOrderedBatch(ctx, db, *b.batch, destTable, b.index, order, log)
+				order.Done(b.index, err)
+				if err != nil {
+					continue
+				}
+
+				if sleep > 0 {
+					time.Sleep(sleep)
+				}
+
+				err = throttle.Wait(ctx)
+				if err != nil {
+					order.Done(b.index+1, err)
+				}
+			}
+		}()
+	}
+
+	batch := first
+	var err error
+	for i := 1; batch != nil; i++ {
+		if order.Err() != nil {
+			break
+		}
+		batches <- indexedBatch{index: i, batch: batch}
+
+		batch, err = next(i + 1)
+		if err != nil {
+			order.Done(i+1, err)
+			break
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	return order.Err()
+}
+
+func runOrderedBatch(ctx context.Context, db *sql.DB, batch fillBatch, destTable Table, i int, order *commitOrder, log io.Writer) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, batch.Query)
+	if err != nil {
+		return err
+	}
+
+	err = order.Wait(i)
+	if err != nil {
+		return err
+	}
+
+	logSQL(log, batch.Query)
+
+	err = saveCheckpoint(ctx, tx, destTable, batch.Checkpoint)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type commitOrder struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	next int
+	err  error
+}
+
+func newCommitOrder(first int) *commitOrder {
+	o := &commitOrder{next: first}
+	o.cond = sync.NewCond(&o.mu)
+	return o
+}
+
+// Wait blocks until batch i is next to commit or another batch has failed
+func (o *commitOrder) Wait(i int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for o.next != i && o.err == nil {
+		o.cond.Wait()
+	}
+	return o.err
+}
+
+func (o *commitOrder) Done(i int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err != nil {
+		if o.err == nil {
+			o.err = err
+		}
+	} else if o.next == i {
+		o.next = i + 1
+	}
+	o.cond.Broadcast()
+}
+
+func (o *commitOrder) Err() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
+
+// partitionedRange returns the start of the first partition and the end
+// of the last, or nil if rows don't need to be in a range
+func partitionedRange(ctx context.Context, db *sql.DB, table Table, settings Settings) (*Bound, *Bound, error) {
+	if !settings.Partitioned() || !settings.Ranged() || settings.Default {
+		return nil, nil, nil
+	}
+
+	partitions, err := table.PartitionBounds(ctx, db, settings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	starting, ending := outerBounds(partitions)
+	return starting, ending, nil
+}
+
+// outerBounds returns the min start and max end, or nil without partitions
+func outerBounds(partitions []PartitionRange) (*Bound, *Bound) {
+	var starting, ending *Bound
+	for _, partition := range partitions {
+		start := partition.Start
+		end := partition.End
+		if starting == nil || start.Before(*starting) {
+			starting = &start
+		}
+		if ending == nil || ending.Before(end) {
+			ending = &end
+		}
+	}
+	return starting, ending
+}
@@ -0,0 +1,272 @@
+// Package pgslice provides Postgres partitioning as easy as pie
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func createTable(name string) Table {
+	schema := "public"
+	if strings.Contains(name, ".") {
+		parts := strings.SplitN(name, ".", 2)
+		schema = parts[0]
+		name = parts[1]
+	}
+	return Table{Schema: schema, Name: name}
+}
+
+func fetchServerVersionNum(ctx context.Context, db *sql.DB) (int, error) {
+	var num int
+	err := db.QueryRowContext(ctx, "SHOW server_version_num").Scan(&num)
+	return num, err
+}
+
+func schemaExists(ctx context.Context, db *sql.DB, schema string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", schema).Scan(&exists)
+	return exists, err
+}
+
+func quoteTable(table Table) string {
+	return strings.Join([]string{quoteIdent(table.Schema), quoteIdent(table.Name)}, ".")
+}
+
+func quoteColumns(key []string) string {
+	quotedKey := make([]string, len(key))
+	for i, k := range key {
+		quotedKey[i] = quoteIdent(k)
+	}
+	return strings.Join(quotedKey, ", ")
+}
+
+// keyCondition compares a key to values using a row comparison for composite keys
+func keyCondition(key []string, op string, values []string) string {
+	quotedValues := make([]string, len(values))
+	for i, v := range values {
+		quotedValues[i] = pq.QuoteLiteral(v)
+	}
+	if len(key) == 1 {
+		return fmt.Sprintf("%s %s %s", quoteIdent(key[0]), op, quotedValues[0])
+	}
+	return fmt.Sprintf("(%s) %s (%s)", quoteColumns(key), op, strings.Join(quotedValues, ", "))
+}
+
+func quoteIdent(column string) string {
+	return pq.QuoteIdentifier(column)
+}
+
+func contains(s []string, e string) bool {
+	return slices.Contains(s, e)
+}
+
+// defaultLockTimeout is used when options don't have a lock timeout
+const defaultLockTimeout = "5s"
+
+var periods = []string{"hour", "day", "week", "month", "quarter", "year"}
+
+// roundDate rounds down in the location of the time
+func roundDate(t time.Time, period string) time.Time {
+	loc := t.Location()
+	switch period {
+	case "hour":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	case "week":
+		// ISO weeks start on Monday
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	case "quarter":
+		return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, loc)
+}
+
+func nameFormat(period string) string {
+	switch period {
+	case "hour":
+		return "2006010215"
+	case "day":
+		return "20060102"
+	case "month":
+		return "200601"
+	}
+	return "2006"
+}
+
+func partitionSuffix(t time.Time, period string) string {
+	switch period {
+	case "week":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%dw%02d", year, week)
+	case "quarter":
+		return fmt.Sprintf("%dq%d", t.Year(), (int(t.Month())-1)/3+1)
+	}
+	return t.Format(nameFormat(period))
+}
+
+// sqlDate formats a time in its location for the column cast. Bounds
+// for timestamptz include the offset and epoch bounds are numbers.
+func sqlDate(t time.Time, cast string, addCast bool) string {
+	switch cast {
+	case "epoch":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "epoch_ms":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	}
+
+	strFmt := "2006-01-02"
+	if cast == "timestamp" {
+		strFmt = "2006-01-02 15:04:05"
+	} else if cast == "timestamptz" {
+		if t.Location() == time.UTC {
+			strFmt = "2006-01-02 15:04:05 UTC"
+		} else {
+			strFmt = "2006-01-02 15:04:05-07:00"
+		}
+	}
+	str := fmt.Sprintf("'%s'", t.Format(strFmt))
+	if addCast {
+		return fmt.Sprintf("%s::%s", str, cast)
+	}
+	return str
+}
+
+func advanceDate(date time.Time, period string, count int) time.Time {
+	switch period {
+	case "hour":
+		return date.Add(time.Duration(count) * time.Hour)
+	case "day":
+		return date.AddDate(0, 0, count)
+	case "week":
+		return date.AddDate(0, 0, count*7)
+	case "month":
+		return date.AddDate(0, count, 0)
+	case "quarter":
+		return date.AddDate(0, count*3, 0)
+	}
+	return date.AddDate(count, 0, 0)
+}
+
+func quoteNoSchema(table Table) string {
+	return quoteIdent(table.Name)
+}
+
+// Error is returned for invalid options and unexpected database state
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func abort(message string) error {
+	return &Error{Message: message}
+}
+
+func makeIndexDef(def string, table Table) string {
+	// indexes on partitioned tables are ON ONLY
+	re1 := regexp.MustCompile(` ON (ONLY )?\S+ USING `)
+	re2 := regexp.MustCompile(` INDEX .+ ON `)
+	def = re1.ReplaceAllString(def, fmt.Sprintf(" ON %s USING ", quoteTable(table)))
+	def = re2.ReplaceAllString(def, " INDEX ON ")
+	return def + ";"
+}
+
+func makeFkDef(def string, table Table) string {
+	return "ALTER TABLE " + quoteTable(table) + " ADD " + def + ";"
+}
+
+// makeFkLocks returns locks for the table and the referenced table
+func makeFkLocks(def string, table Table) []Lock {
+	locks := []Lock{newLock(ShareRowExclusive, table)}
+	matches := regexp.MustCompile(`REFERENCES (.+?)\(`).FindStringSubmatch(def)
+	if matches != nil {
+		locks = append(locks, Lock{Mode: ShareRowExclusive, Table: matches[1]})
+	}
+	return locks
+}
+
+// makeTriggerDef routes rows to partitions, and rows outside them to
+// the default partition when the settings have one
+func makeTriggerDef(triggerName string, partitions []PartitionRange, settings Settings, current Bound, defaultPartition Table) string {
+	if len(partitions) == 0 && !settings.Default {
+		return fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s()
+    RETURNS trigger AS $$
+    BEGIN
+        RAISE EXCEPTION 'Create partitions first.';
+    END;
+    $$ LANGUAGE plpgsql;`, quoteIdent(triggerName))
+	}
+
+	currentDefs := []string{}
+	futureDefs := []string{}
+	pastDefs := []string{}
+
+	partitions = slices.Clone(partitions)
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].Start.Before(partitions[j].Start)
+	})
+
+	field := quoteIdent(settings.Column)
+	for _, p := range partitions {
+		sql := fmt.Sprintf(`(NEW.%s >= %s AND NEW.%s < %s) THEN
+            INSERT INTO %s VALUES (NEW.*);`, field, settings.SQL(p.Start, true), field, settings.SQL(p.End, true), quoteTable(p.Table))
+
+		if p.Start.Before(current) {
+			pastDefs = append(pastDefs, sql)
+		} else if p.End.Before(current) {
+			currentDefs = append(currentDefs, sql)
+		} else {
+			futureDefs = append(futureDefs, sql)
+		}
+	}
+
+	// order by current period, future periods asc, past periods desc
+	// TODO reverse past defs
+	triggerDefs := append(currentDefs, futureDefs...)
+	triggerDefs = append(triggerDefs, pastDefs...)
+
+	message := "Date out of range. Ensure partitions are created."
+	if settings.Numeric() {
+		message = "Value out of range. Ensure partitions are created."
+	}
+	elseDef := fmt.Sprintf("RAISE EXCEPTION '%s';", message)
+	if settings.Default {
+		elseDef = fmt.Sprintf("INSERT INTO %s VALUES (NEW.*);", quoteTable(defaultPartition))
+
+		if len(triggerDefs) == 0 {
+			return fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s()
+    RETURNS trigger AS $$
+    BEGIN
+        %s
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;`, quoteIdent(triggerName), elseDef)
+		}
+	}
+
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s()
+    RETURNS trigger AS $$
+    BEGIN
+        IF %s
+        ELSE
+            %s
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;`, quoteIdent(triggerName), strings.Join(triggerDefs, "\n        ELSIF "), elseDef)
+}
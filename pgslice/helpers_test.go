@@ -0,0 +1,22 @@
+package pgslice
+
+import "testing"
+
+func TestMakeIndexDef(t *testing.T) {
+	table := createTable("Posts_intermediate")
+	tests := []struct {
+		def      string
+		expected string
+	}{
+		{`CREATE INDEX "Posts_createdAt_idx" ON public."Posts" USING btree ("createdAt")`, `CREATE INDEX ON "public"."Posts_intermediate" USING btree ("createdAt");`},
+		{`CREATE INDEX "Posts_createdAt_idx" ON ONLY public."Posts" USING btree ("createdAt")`, `CREATE INDEX ON "public"."Posts_intermediate" USING btree ("createdAt");`},
+		{`CREATE UNIQUE INDEX "Posts_Id_createdAt_idx" ON ONLY public."Posts" USING btree ("Id", "createdAt")`, `CREATE UNIQUE INDEX ON "public"."Posts_intermediate" USING btree ("Id", "createdAt");`},
+	}
+
+	for _, tt := range tests {
+		actual := makeIndexDef(tt.def, table)
+		if actual != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, actual)
+		}
+	}
+}
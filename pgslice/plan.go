@@ -0,0 +1,162 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	AccessShare          = "ACCESS SHARE"
+	RowExclusive         = "ROW EXCLUSIVE"
+	ShareUpdateExclusive = "SHARE UPDATE EXCLUSIVE"
+	Share                = "SHARE"
+	ShareRowExclusive    = "SHARE ROW EXCLUSIVE"
+	AccessExclusive      = "ACCESS EXCLUSIVE"
+)
+
+// Lock is a table lock a step is expected to take
+type Lock struct {
+	Mode  string `json:"mode"`
+	Table string `json:"table"`
+}
+
+type Step struct {
+	Description string `json:"description"`
+	SQL         string `json:"sql"`
+	Transaction bool   `json:"transaction"`
+	Locks       []Lock `json:"locks"`
+}
+
+// Plan is an ordered list of steps for a command. Consecutive steps
+// that run in a transaction share a single transaction.
+type Plan struct {
+	Steps []Step `json:"steps"`
+}
+
+type ExecuteOptions struct {
+	DryRun bool
+	Log    io.Writer
+}
+
+func newLock(mode string, table Table) Lock {
+	return Lock{Mode: mode, Table: table.FullName()}
+}
+
+func (p *Plan) Add(description string, sql string, locks ...Lock) {
+	p.Steps = append(p.Steps, Step{Description: description, SQL: sql, Transaction: true, Locks: append([]Lock{}, locks...)})
+}
+
+func (p *Plan) AddWithoutTransaction(description string, sql string, locks ...Lock) {
+	p.Steps = append(p.Steps, Step{Description: description, SQL: sql, Transaction: false, Locks: append([]Lock{}, locks...)})
+}
+
+func (p *Plan) Empty() bool {
+	return len(p.Steps) == 0
+}
+
+// Execute runs the steps, or only logs them for a dry run
+func (p *Plan) Execute(ctx context.Context, db *sql.DB, opts ExecuteOptions) error {
+	for i := 0; i < len(p.Steps); {
+		if !p.Steps[i].Transaction {
+			logSQL(opts.Log, p.Steps[i].SQL)
+			if !opts.DryRun {
+				_, err := db.ExecContext(ctx, p.Steps[i].SQL)
+				if err != nil {
+					return err
+				}
+			}
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(p.Steps) && p.Steps[j].Transaction {
+			j++
+		}
+		err := executeTransaction(ctx, db, p.Steps[i:j], opts)
+		if err != nil {
+			return err
+		}
+		i = j
+	}
+	return nil
+}
+
+func executeTransaction(ctx context.Context, db *sql.DB, steps []Step, opts ExecuteOptions) error {
+	logSQL(opts.Log, "BEGIN;")
+
+	if opts.DryRun {
+		for _, step := range steps {
+			logSQL(opts.Log, step.SQL)
+		}
+		logSQL(opts.Log, "COMMIT;")
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, step := range steps {
+		logSQL(opts.Log, step.SQL)
+		_, err := tx.ExecContext(ctx, step.SQL)
+		if err != nil {
+			return err
+		}
+	}
+
+	logSQL(opts.Log, "COMMIT;")
+	return tx.Commit()
+}
+
+// SQL returns a script with a comment for each step
+func (p *Plan) SQL() string {
+	var sb strings.Builder
+	inTransaction := false
+	for _, step := range p.Steps {
+		if step.Transaction && !inTransaction {
+			sb.WriteString("BEGIN;\n\n")
+		} else if !step.Transaction && inTransaction {
+			sb.WriteString("COMMIT;\n\n")
+		}
+		inTransaction = step.Transaction
+
+		fmt.Fprintf(&sb, "-- %s\n", step.Description)
+		for _, lock := range step.Locks {
+			fmt.Fprintf(&sb, "-- lock: %s on %s\n", lock.Mode, lock.Table)
+		}
+		sb.WriteString(step.SQL)
+		sb.WriteString("\n\n")
+	}
+	if inTransaction {
+		sb.WriteString("COMMIT;\n")
+	}
+	return sb.String()
+}
+
+func (p *Plan) JSON() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+func ParsePlan(data []byte) (*Plan, error) {
+	var plan Plan
+	err := json.Unmarshal(data, &plan)
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func logSQL(w io.Writer, s string) {
+	if w == nil {
+		return
+	}
+	fmt.Fprintln(w, s)
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,280 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type PrepOptions struct {
+	Table            string
+	Column           string
+	Period           string
+	Interval         int64
+	Strategy         string
+	SubColumn        string
+	SubModulus       int
+	DefaultPartition bool
+	TimeZone         string
+	Cast             string
+	NameTemplate     string
+	PartitionSchema  string
+	NoPartition      bool
+	TriggerBased     bool
+}
+
+func Prep(ctx context.Context, db *sql.DB, opts PrepOptions) (*Plan, error) {
+	column := opts.Column
+	period := opts.Period
+	interval := opts.Interval
+	strategy := opts.Strategy
+	if strategy == "" {
+		strategy = "range"
+	}
+
+	partition := !opts.NoPartition
+	triggerBased := opts.TriggerBased
+
+	table := createTable(opts.Table)
+	intermediateTable := table.IntermediateTable()
+	triggerName := table.TriggerName()
+
+	if !partition {
+		if column != "" || period != "" || interval != 0 || opts.Strategy != "" || opts.SubColumn != "" || opts.DefaultPartition || opts.TimeZone != "" || opts.Cast != "" || opts.NameTemplate != "" || opts.PartitionSchema != "" {
+			return nil, abort("Usage: pgslice prep TABLE --no-partition")
+		}
+		if triggerBased {
+			return nil, abort("Can't use --trigger-based and --no-partition")
+		}
+	}
+
+	tableExists, err := table.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !tableExists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	intermediateTableExists, err := intermediateTable.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if intermediateTableExists {
+		return nil, abort(fmt.Sprintf("Table already exists: %s", intermediateTable.FullName()))
+	}
+
+	if !contains(strategies, strategy) {
+		return nil, abort("Invalid strategy: " + strategy)
+	}
+
+	if opts.TimeZone != "" {
+		if strategy != "range" || interval != 0 {
+			return nil, abort("--time-zone requires a period")
+		}
+		_, err := time.LoadLocation(opts.TimeZone)
+		if err != nil {
+			return nil, abort("Invalid time zone: " + opts.TimeZone)
+		}
+	}
+
+	if opts.Cast != "" && (strategy != "range" || interval != 0) {
+		return nil, abort("--cast requires a period")
+	}
+
+	if (opts.NameTemplate != "" || opts.PartitionSchema != "") && strategy != "range" {
+		return nil, abort("--name-template and --partition-schema require range partitioning")
+	}
+
+	if opts.PartitionSchema != "" {
+		exists, err := schemaExists(ctx, db, opts.PartitionSchema)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, abort("Schema not found: " + opts.PartitionSchema)
+		}
+	}
+
+	if opts.SubColumn != "" {
+		if strategy != "range" {
+			return nil, abort("--sub-column requires range partitioning")
+		}
+		if triggerBased {
+			return nil, abort("Can't use --trigger-based and --sub-column")
+		}
+		if opts.SubModulus < 1 {
+			return nil, abort("Usage: pgslice prep TABLE COLUMN PERIOD --sub-column COLUMN --sub-modulus N")
+		}
+	} else if opts.SubModulus != 0 {
+		return nil, abort("--sub-modulus requires --sub-column")
+	}
+
+	var settings Settings
+	if partition && strategy != "range" {
+		if column == "" {
+			return nil, abort("Usage: pgslice prep TABLE COLUMN --strategy " + strategy)
+		}
+		if period != "" || interval != 0 {
+			return nil, abort(fmt.Sprintf("Can't use a period or --interval with --strategy %s", strategy))
+		}
+		if triggerBased {
+			return nil, abort(fmt.Sprintf("Can't use --trigger-based with --strategy %s", strategy))
+		}
+
+		columns, err := table.Columns(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+
+		if !contains(columns, column) {
+			return nil, abort(fmt.Sprintf("Column not found: %s", column))
+		}
+
+		settings.Column = column
+		settings.Strategy = strategy
+		settings.Cast, err = table.ColumnDataType(ctx, db, column)
+		if err != nil {
+			return nil, err
+		}
+	} else if partition {
+		if column == "" || (period == "" && interval == 0) {
+			return nil, abort("Usage: pgslice prep TABLE COLUMN PERIOD")
+		}
+		if period != "" && interval != 0 {
+			return nil, abort("Can't use a period and --interval")
+		}
+		if interval < 0 {
+			return nil, abort(fmt.Sprintf("Invalid interval: %d", interval))
+		}
+
+		columns, err := table.Columns(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+
+		if !contains(columns, column) {
+			return nil, abort(fmt.Sprintf("Column not found: %s", column))
+		}
+
+		if opts.SubColumn != "" && !contains(columns, opts.SubColumn) {
+			return nil, abort(fmt.Sprintf("Column not found: %s", opts.SubColumn))
+		}
+
+		settings.Column = column
+		settings.Period = period
+		settings.Interval = interval
+		settings.SubColumn = opts.SubColumn
+		settings.SubModulus = opts.SubModulus
+		settings.TimeZone = opts.TimeZone
+		settings.NameTemplate = opts.NameTemplate
+		settings.PartitionSchema = opts.PartitionSchema
+
+		if opts.NameTemplate != "" && !settings.ValidTemplate(opts.NameTemplate) {
+			return nil, abort("Invalid name template: " + opts.NameTemplate)
+		}
+
+		if interval > 0 {
+			dataType, err := table.ColumnDataType(ctx, db, column)
+			if err != nil {
+				return nil, err
+			}
+			if !contains(intervalCasts, dataType) {
+				return nil, abort("--interval requires an integer column")
+			}
+			settings.Cast = dataType
+		} else {
+			if !contains(periods, period) {
+				return nil, abort("Invalid period: " + period)
+			}
+
+			settings.Cast, err = table.ColumnCast(ctx, db, column, opts.Cast)
+			if err != nil {
+				return nil, err
+			}
+			if period == "hour" && settings.Cast == "date" {
+				return nil, abort("Period hour requires a timestamp column")
+			}
+			if !settings.validTimeZone() {
+				return nil, abort("Period hour requires UTC")
+			}
+		}
+	}
+
+	plan := &Plan{}
+
+	serverVersionNum, err := fetchServerVersionNum(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	declarative := serverVersionNum >= 100000 && !triggerBased
+
+	if (settings.Strategy == "hash" || settings.Subpartitioned()) && serverVersionNum < 110000 {
+		return nil, abort("Hash partitioning requires Postgres 11+")
+	}
+
+	if opts.DefaultPartition {
+		if settings.Strategy == "hash" {
+			return nil, abort("Can't use --default-partition with --strategy hash")
+		}
+		if declarative && serverVersionNum < 110000 {
+			return nil, abort("--default-partition requires Postgres 11+")
+		}
+		settings.Default = true
+	}
+	if settings.Strategy == "list" && !declarative {
+		return nil, abort("--strategy list requires Postgres 10+")
+	}
+
+	var indexDefs []string
+
+	if declarative && partition {
+		plan.Add("Create partitioned intermediate table", fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE INCLUDING COMMENTS) PARTITION BY %s (%s);", quoteTable(intermediateTable), quoteTable(table), strings.ToUpper(strategy), quoteIdent(column)), newLock(AccessShare, table))
+
+		if serverVersionNum >= 110000 {
+			indexDefs, err = table.IndexDefs(ctx, db)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, def := range indexDefs {
+				plan.Add("Copy index", makeIndexDef(def, intermediateTable), newLock(Share, intermediateTable))
+			}
+		}
+
+		// add comment
+		settings.Declarative = true
+		addSaveSettings(plan, intermediateTable, triggerName, settings)
+	} else {
+		plan.Add("Create intermediate table", fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING ALL);", quoteTable(intermediateTable), quoteTable(table)), newLock(AccessShare, table))
+
+		foreignKeys, err := table.ForeignKeys(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, def := range foreignKeys {
+			plan.Add("Copy foreign key", makeFkDef(def, intermediateTable), makeFkLocks(def, intermediateTable)...)
+		}
+	}
+
+	if partition && !declarative {
+		plan.Add("Create insert trigger function", fmt.Sprintf(`CREATE FUNCTION %s()
+    RETURNS trigger AS $$
+    BEGIN
+        RAISE EXCEPTION 'Create partitions first.';
+    END;
+    $$ LANGUAGE plpgsql;`, quoteIdent(triggerName)))
+
+		plan.Add("Create insert trigger", fmt.Sprintf(`CREATE TRIGGER %s
+    BEFORE INSERT ON %s
+    FOR EACH ROW EXECUTE PROCEDURE %s();`, quoteIdent(triggerName), quoteTable(intermediateTable), quoteIdent(triggerName)), newLock(ShareRowExclusive, intermediateTable))
+
+		addSaveSettings(plan, intermediateTable, triggerName, settings)
+	}
+
+	return plan, nil
+}
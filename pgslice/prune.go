@@ -0,0 +1,112 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type PruneOptions struct {
+	Table string
+	Keep  int
+	Drop  bool
+}
+
+func Prune(ctx context.Context, db *sql.DB, opts PruneOptions) (*Plan, error) {
+	table := createTable(opts.Table)
+	triggerName := table.TriggerName()
+	keep := opts.Keep
+	drop := opts.Drop
+
+	if keep < 1 {
+		return nil, abort("Usage: pgslice prune TABLE --keep N")
+	}
+
+	exists, err := table.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	settings, err := fetchSettings(ctx, db, table, table)
+	if err != nil {
+		return nil, err
+	}
+	declarative := settings.Declarative
+
+	if !settings.Partitioned() {
+		return nil, abort(fmt.Sprintf("No settings found: %s", table.FullName()))
+	}
+
+	if !settings.Ranged() {
+		return nil, abort(fmt.Sprintf("Can't prune %s partitions", settings.Strategy))
+	}
+
+	current, err := settings.Current(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := settings.Advance(current, -keep+1)
+
+	partitions, err := table.PartitionBounds(ctx, db, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	prunedPartitions := []Table{}
+	keptPartitions := []PartitionRange{}
+	for _, partition := range partitions {
+		// keep partitions with any rows after the cutoff
+		if !cutoff.Before(partition.End) {
+			prunedPartitions = append(prunedPartitions, partition.Table)
+		} else {
+			keptPartitions = append(keptPartitions, partition)
+		}
+	}
+
+	plan := &Plan{}
+	if len(prunedPartitions) == 0 {
+		return plan, nil
+	}
+
+	if declarative {
+		serverVersionNum, err := fetchServerVersionNum(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+
+		// detach concurrently to avoid blocking reads and writes on the parent
+		// but it can't run inside a transaction or with a default partition
+		concurrently := serverVersionNum >= 140000 && !settings.Default
+
+		for _, partition := range prunedPartitions {
+			if concurrently {
+				plan.AddWithoutTransaction("Detach partition", fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s CONCURRENTLY;", quoteTable(table), quoteTable(partition)), newLock(ShareUpdateExclusive, table), newLock(AccessExclusive, partition))
+				if drop {
+					plan.AddWithoutTransaction("Drop partition", fmt.Sprintf("DROP TABLE %s;", quoteTable(partition)), newLock(AccessExclusive, partition))
+				}
+			} else {
+				plan.Add("Detach partition", fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s;", quoteTable(table), quoteTable(partition)), newLock(AccessExclusive, table), newLock(AccessExclusive, partition))
+				if drop {
+					plan.Add("Drop partition", fmt.Sprintf("DROP TABLE %s;", quoteTable(partition)), newLock(AccessExclusive, partition))
+				}
+			}
+		}
+
+		return plan, nil
+	}
+
+	for _, partition := range prunedPartitions {
+		plan.Add("Remove partition", fmt.Sprintf("ALTER TABLE %s NO INHERIT %s;", quoteTable(partition), quoteTable(table)), newLock(AccessExclusive, partition), newLock(ShareUpdateExclusive, table))
+		if drop {
+			plan.Add("Drop partition", fmt.Sprintf("DROP TABLE %s;", quoteTable(partition)), newLock(AccessExclusive, partition))
+		}
+	}
+
+	// update trigger based on remaining partitions
+	plan.Add("Update insert trigger function", makeTriggerDef(triggerName, keptPartitions, settings, current, table.DefaultPartition()))
+
+	return plan, nil
+}
@@ -0,0 +1,186 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type RescueOptions struct {
+	Table        string
+	Intermediate bool
+}
+
+// Rescue moves rows from the default partition to partitions for their
+// ranges, creating partitions as needed. Postgres won't create a partition
+// while the default partition has rows in its range, so the default
+// partition is detached while rows are moved for declarative partitioning.
+// This is in one transaction so rows are never missing from the table,
+// which blocks reads and writes on the table until the rows are moved.
+func Rescue(ctx context.Context, db *sql.DB, opts RescueOptions) (*Plan, error) {
+	originalTable := createTable(opts.Table)
+
+	table := originalTable
+	if opts.Intermediate {
+		table = table.IntermediateTable()
+	}
+	triggerName := originalTable.TriggerName()
+	defaultPartition := originalTable.DefaultPartition()
+
+	exists, err := table.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	settings, err := fetchSettings(ctx, db, originalTable, table)
+	if err != nil {
+		return nil, err
+	}
+	declarative := settings.Declarative
+
+	if !settings.Partitioned() {
+		return nil, abort(fmt.Sprintf("No settings found: %s", table.FullName()))
+	}
+
+	if !settings.Ranged() {
+		return nil, abort(fmt.Sprintf("Can't rescue rows for %s partitions", settings.Strategy))
+	}
+
+	exists, err = defaultPartition.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", defaultPartition.FullName()))
+	}
+
+	starts, err := defaultStarts(ctx, db, defaultPartition, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	if len(starts) == 0 {
+		return plan, nil
+	}
+
+	creator, err := newPartitionCreator(ctx, db, originalTable, table, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := table.Columns(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	fields := quoteColumns(columns)
+
+	if declarative {
+		plan.Add("Detach default partition", fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s;", quoteTable(table), quoteTable(defaultPartition)), newLock(AccessExclusive, table), newLock(AccessExclusive, defaultPartition))
+	}
+
+	existingRanges, err := table.PartitionBounds(ctx, db, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := []partitionDef{}
+	addedRanges := []PartitionRange{}
+	for _, start := range starts {
+		def := rangePartitionDef(originalTable, settings, start)
+		defs = append(defs, def)
+
+		if !overlaps(existingRanges, def) {
+			creator.add(plan, def)
+			addedRanges = append(addedRanges, PartitionRange{Table: def.table, Start: def.start, End: def.end})
+		}
+	}
+
+	if !declarative && len(addedRanges) > 0 {
+		partitions := append(existingRanges, addedRanges...)
+
+		current, err := settings.Current(ctx, db, originalTable)
+		if err != nil {
+			return nil, err
+		}
+		plan.Add("Update insert trigger function", makeTriggerDef(triggerName, partitions, settings, current, defaultPartition))
+	}
+
+	for _, def := range defs {
+		plan.Add("Move rows", fmt.Sprintf(`WITH rows AS (
+    DELETE FROM %s WHERE %s RETURNING %s
+)
+INSERT INTO %s (%s) SELECT %s FROM rows;`, quoteTable(defaultPartition), def.check, fields, quoteTable(table), fields, fields), newLock(RowExclusive, defaultPartition), newLock(RowExclusive, table))
+	}
+
+	if declarative {
+		plan.Add("Attach default partition", fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s DEFAULT;", quoteTable(table), quoteTable(defaultPartition)), newLock(AccessExclusive, table), newLock(AccessExclusive, defaultPartition))
+	}
+
+	return plan, nil
+}
+
+// defaultStarts returns the start of each range with rows in the default partition
+func defaultStarts(ctx context.Context, db *sql.DB, defaultPartition Table, settings Settings) ([]Bound, error) {
+	field := quoteIdent(settings.Column)
+
+	var expr string
+	if settings.Numeric() {
+		expr = fmt.Sprintf("(floor(%s / %d::numeric) * %d)::bigint::text", field, settings.Interval, settings.Interval)
+	} else {
+		timeZone := pq.QuoteLiteral(settings.Location().String())
+
+		var value string
+		switch settings.Cast {
+		case "timestamptz":
+			value = fmt.Sprintf("%s AT TIME ZONE %s", field, timeZone)
+		case "epoch":
+			value = fmt.Sprintf("to_timestamp(%s) AT TIME ZONE %s", field, timeZone)
+		case "epoch_ms":
+			value = fmt.Sprintf("to_timestamp(%s / 1000.0) AT TIME ZONE %s", field, timeZone)
+		default:
+			value = field + "::timestamp"
+		}
+		expr = fmt.Sprintf("to_char(date_trunc('%s', %s), 'YYYY-MM-DD HH24:MI:SS')", settings.Period, value)
+	}
+
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s IS NOT NULL", expr, quoteTable(defaultPartition), field)
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	starts := []Bound{}
+	for rows.Next() {
+		var s string
+		err := rows.Scan(&s)
+		if err != nil {
+			return nil, err
+		}
+
+		var start Bound
+		if settings.Numeric() {
+			start.Value, err = strconv.ParseInt(s, 10, 64)
+		} else {
+			start.Time, err = time.ParseInLocation("2006-01-02 15:04:05", s, settings.Location())
+		}
+		if err != nil {
+			return nil, err
+		}
+		starts = append(starts, start)
+	}
+
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+	return starts, nil
+}
@@ -0,0 +1,556 @@
+package pgslice
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Settings are saved as JSON in a comment on the intermediate table
+// (or the insert trigger for trigger-based partitioning) by prep
+type Settings struct {
+	Column          string        `json:"column"`
+	Period          string        `json:"period,omitempty"`
+	Interval        int64         `json:"interval,omitempty"`
+	Strategy        string        `json:"strategy,omitempty"`
+	Cast            string        `json:"cast"`
+	SubColumn       string        `json:"sub_column,omitempty"`
+	SubModulus      int           `json:"sub_modulus,omitempty"`
+	Default         bool          `json:"default,omitempty"`
+	TimeZone        string        `json:"time_zone,omitempty"`
+	NameTemplate    string        `json:"name_template,omitempty"`
+	PartitionSchema string        `json:"partition_schema,omitempty"`
+	PeriodRanges    []PeriodRange `json:"period_ranges,omitempty"`
+	Declarative     bool          `json:"-"`
+}
+
+// PeriodRange is a range with a different period, from split or merge
+type PeriodRange struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Period string `json:"period"`
+}
+
+var SettingKeys = []string{"column", "period", "interval", "strategy", "cast", "sub_column", "sub_modulus", "default", "time_zone", "name_template", "partition_schema", "period_ranges"}
+
+var strategies = []string{"range", "list", "hash"}
+
+// Casts are how bounds are written for periods and intervals
+var (
+	periodCasts   = []string{"timestamptz", "timestamp", "date", "epoch", "epoch_ms"}
+	intervalCasts = []string{"smallint", "integer", "bigint"}
+)
+
+// Bound is the start or end of a partition range, a time for
+// periods or a value for intervals
+type Bound struct {
+	Time  time.Time
+	Value int64
+}
+
+func (b Bound) Before(o Bound) bool {
+	if b.Time.Equal(o.Time) {
+		return b.Value < o.Value
+	}
+	return b.Time.Before(o.Time)
+}
+
+func (b Bound) String() string {
+	if b.Time.IsZero() {
+		return strconv.FormatInt(b.Value, 10)
+	}
+	return b.Time.Format(time.RFC3339)
+}
+
+// Partitioned returns false if no settings were found
+func (s Settings) Partitioned() bool {
+	return s.Period != "" || s.Interval > 0 || !s.Ranged()
+}
+
+// Ranged returns true for range partitioning, which is the default
+func (s Settings) Ranged() bool {
+	return s.Strategy == "" || s.Strategy == "range"
+}
+
+// Numeric returns true for integer ranges
+func (s Settings) Numeric() bool {
+	return s.Interval > 0
+}
+
+// Subpartitioned returns true if each partition is hash partitioned
+func (s Settings) Subpartitioned() bool {
+	return s.SubColumn != ""
+}
+
+func (s Settings) Comment() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		// only strings, numbers, and booleans
+		panic(err)
+	}
+	return string(data)
+}
+
+// addSaveSettings adds a step to save settings on the table, or
+// on the insert trigger for trigger-based partitioning
+func addSaveSettings(plan *Plan, table Table, triggerName string, settings Settings) {
+	if settings.Declarative {
+		plan.Add("Save settings", fmt.Sprintf("COMMENT ON TABLE %s is %s;", quoteTable(table), pq.QuoteLiteral(settings.Comment())), newLock(ShareUpdateExclusive, table))
+	} else {
+		plan.Add("Save settings", fmt.Sprintf("COMMENT ON TRIGGER %s ON %s IS %s;", quoteIdent(triggerName), quoteTable(table), pq.QuoteLiteral(settings.Comment())))
+	}
+}
+
+// parseSettings parses a settings comment, which is JSON or the
+// column:x,period:y,cast:z format from earlier versions
+func parseSettings(comment string) (Settings, error) {
+	var settings Settings
+	if strings.HasPrefix(comment, "{") {
+		err := json.Unmarshal([]byte(comment), &settings)
+		if err != nil {
+			return settings, abort("Invalid settings: " + err.Error())
+		}
+		if settings.TimeZone != "" {
+			_, err := time.LoadLocation(settings.TimeZone)
+			if err != nil {
+				return settings, abort("Invalid time zone: " + settings.TimeZone)
+			}
+		}
+		return settings, nil
+	}
+
+	for _, part := range strings.Split(comment, ",") {
+		key, value, _ := strings.Cut(part, ":")
+		// ignore comments not from pgslice
+		if !contains(SettingKeys, key) {
+			continue
+		}
+		err := settings.Set(key, value)
+		if err != nil {
+			return settings, err
+		}
+	}
+	return settings, nil
+}
+
+// Set updates a setting from its key and string value
+func (s *Settings) Set(key string, value string) error {
+	switch key {
+	case "column":
+		s.Column = value
+	case "period":
+		if value != "" && !contains(periods, value) {
+			return abort("Invalid period: " + value)
+		}
+		s.Period = value
+	case "cast":
+		if !contains(periodCasts, value) && !contains(intervalCasts, value) {
+			return abort("Invalid cast: " + value)
+		}
+		s.Cast = value
+	case "strategy":
+		if value != "" && !contains(strategies, value) {
+			return abort("Invalid strategy: " + value)
+		}
+		s.Strategy = value
+	case "time_zone":
+		_, err := time.LoadLocation(value)
+		if err != nil {
+			return abort("Invalid time zone: " + value)
+		}
+		s.TimeZone = value
+	case "default":
+		if value != "true" && value != "false" {
+			return abort("Invalid default: " + value)
+		}
+		s.Default = value == "true"
+	case "name_template":
+		s.NameTemplate = value
+	case "partition_schema":
+		s.PartitionSchema = value
+	case "period_ranges":
+		var ranges []PeriodRange
+		err := json.Unmarshal([]byte(value), &ranges)
+		if err != nil {
+			return abort("Invalid period_ranges: " + value)
+		}
+		s.PeriodRanges = ranges
+	case "sub_column":
+		s.SubColumn = value
+	case "sub_modulus":
+		modulus, err := strconv.Atoi(value)
+		if err != nil {
+			return abort("Invalid sub_modulus: " + value)
+		}
+		s.SubModulus = modulus
+	case "interval":
+		interval, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return abort("Invalid interval: " + value)
+		}
+		s.Interval = interval
+	default:
+		return abort("Unknown setting: " + key)
+	}
+	return nil
+}
+
+// validCast is false when the cast doesn't match the period or interval.
+// The cast for list and hash partitioning is the column type.
+func (s Settings) validCast() bool {
+	if !s.Ranged() {
+		return true
+	}
+	if s.Numeric() {
+		return contains(intervalCasts, s.Cast)
+	}
+	if s.Period == "hour" && s.Cast == "date" {
+		return false
+	}
+	return contains(periodCasts, s.Cast)
+}
+
+// validTimeZone is false for hour partitions in a time zone other than
+// UTC, since local hours repeat when clocks go back
+func (s Settings) validTimeZone() bool {
+	return s.Period != "hour" || s.TimeZone == "" || s.TimeZone == "UTC"
+}
+
+// Location is the time zone for rounding, bounds, and names, which defaults to UTC
+func (s Settings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		// validated when parsed
+		return time.UTC
+	}
+	return loc
+}
+
+// Round returns the start of the partition containing a bound
+func (s Settings) Round(b Bound) Bound {
+	if s.Numeric() {
+		start := b.Value - b.Value%s.Interval
+		if b.Value < 0 && b.Value%s.Interval != 0 {
+			start -= s.Interval
+		}
+		return Bound{Value: start}
+	}
+	return Bound{Time: roundDate(b.Time.In(s.Location()), s.Period)}
+}
+
+func (s Settings) Advance(b Bound, count int) Bound {
+	if s.Numeric() {
+		return Bound{Value: b.Value + int64(count)*s.Interval}
+	}
+	return Bound{Time: advanceDate(b.Time, s.Period, count)}
+}
+
+func (s Settings) Suffix(b Bound) string {
+	if s.Numeric() {
+		return strconv.FormatInt(b.Value, 10)
+	}
+	return partitionSuffix(b.Time, s.Period)
+}
+
+func (s Settings) SQL(b Bound, addCast bool) string {
+	if s.Numeric() {
+		return strconv.FormatInt(b.Value, 10)
+	}
+	return sqlDate(b.Time, s.Cast, addCast)
+}
+
+// DefaultTemplate is the partition name without a template
+const DefaultTemplate = "{table}_{suffix}"
+
+// templateTokens are the tokens a template needs to be unique for a period
+var templateTokens = map[string][]string{
+	"hour":    {"{YYYY}", "{MM}", "{DD}", "{HH}"},
+	"day":     {"{YYYY}", "{MM}", "{DD}"},
+	"week":    {"{YYYY}", "{WW}"},
+	"month":   {"{YYYY}", "{MM}"},
+	"quarter": {"{YYYY}", "{Q}"},
+	"year":    {"{YYYY}"},
+}
+
+// ValidTemplate returns true if a template gives each partition a unique name
+func (s Settings) ValidTemplate(template string) bool {
+	if strings.Contains(template, "{suffix}") {
+		return true
+	}
+	if s.Numeric() {
+		return false
+	}
+	for _, token := range templateTokens[s.Period] {
+		if !strings.Contains(template, token) {
+			return false
+		}
+	}
+	return true
+}
+
+// PartitionTable returns the partition for a start, which is named by the
+// template and in the partition schema or the schema of the table
+func (s Settings) PartitionTable(originalTable Table, start Bound) Table {
+	template := cmp.Or(s.NameTemplate, DefaultTemplate)
+	replacements := []string{"{table}", originalTable.Name, "{suffix}", s.Suffix(start)}
+	if !s.Numeric() {
+		t := start.Time
+		year := t.Year()
+		_, week := t.ISOWeek()
+		if s.Period == "week" {
+			// weeks belong to ISO years
+			year, _ = t.ISOWeek()
+		}
+		replacements = append(replacements,
+			"{YYYY}", fmt.Sprintf("%04d", year),
+			"{MM}", fmt.Sprintf("%02d", t.Month()),
+			"{DD}", fmt.Sprintf("%02d", t.Day()),
+			"{HH}", fmt.Sprintf("%02d", t.Hour()),
+			"{WW}", fmt.Sprintf("%02d", week),
+			"{Q}", strconv.Itoa((int(t.Month())-1)/3+1),
+		)
+	}
+	name := strings.NewReplacer(replacements...).Replace(template)
+	return Table{Schema: cmp.Or(s.PartitionSchema, originalTable.Schema), Name: name}
+}
+
+var boundRegex = regexp.MustCompile(`FOR VALUES FROM \((.+)\) TO \((.+)\)`)
+
+// ParseRange parses the range of a partition from its bound for declarative
+// partitioning or CHECK constraint for inheritance. It returns false for
+// other partitions, like the default or unbounded ranges.
+func (s Settings) ParseRange(bound string) (Bound, Bound, bool, error) {
+	var matches []string
+	if s.Declarative {
+		matches = boundRegex.FindStringSubmatch(bound)
+	} else {
+		// CHECK constraints look like ((col >= '2026-01-01'::date) AND (col < '2026-01-02'::date))
+		column := fmt.Sprintf("(?:%s|%s)", regexp.QuoteMeta(quoteIdent(s.Column)), regexp.QuoteMeta(s.Column))
+		value := `('(?:[^']|'')*'|-?\d+)(?:::[a-z ]+)?`
+		matches = regexp.MustCompile(column + " >= " + value + `\) AND \(` + column + " < " + value).FindStringSubmatch(bound)
+	}
+	if matches == nil || matches[1] == "MINVALUE" || matches[2] == "MAXVALUE" {
+		return Bound{}, Bound{}, false, nil
+	}
+
+	start, err := s.ParseBound(matches[1])
+	if err != nil {
+		return Bound{}, Bound{}, false, err
+	}
+	end, err := s.ParseBound(matches[2])
+	if err != nil {
+		return Bound{}, Bound{}, false, err
+	}
+	return start, end, true, nil
+}
+
+// ParseBound parses a bound from the catalog, like '2026-01-01 00:00:00+00',
+// or from an option, like 2026-01-01
+func (s Settings) ParseBound(value string) (Bound, error) {
+	if s.Numeric() || s.Cast == "epoch" || s.Cast == "epoch_ms" {
+		v, err := strconv.ParseInt(strings.Trim(value, "'"), 10, 64)
+		if err != nil {
+			return Bound{}, err
+		}
+		switch s.Cast {
+		case "epoch":
+			return Bound{Time: time.Unix(v, 0).In(s.Location())}, nil
+		case "epoch_ms":
+			return Bound{Time: time.UnixMilli(v).In(s.Location())}, nil
+		}
+		return Bound{Value: v}, nil
+	}
+
+	// literals from the catalog are quoted
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+
+	// try with an offset first for timestamptz
+	for _, layout := range []string{"2006-01-02 15:04:05-07:00", "2006-01-02 15:04:05-07", "2006-01-02 15:04:05", "2006-01-02"} {
+		t, err := time.ParseInLocation(layout, value, s.Location())
+		if err == nil {
+			return Bound{Time: t.In(s.Location())}, nil
+		}
+	}
+	return Bound{}, fmt.Errorf("invalid bound: %s", value)
+}
+
+// SetPeriod records the period for a range, replacing overlapping ranges
+func (s *Settings) SetPeriod(start Bound, end Bound, period string) {
+	ranges := []PeriodRange{}
+	for _, r := range s.PeriodRanges {
+		from, err1 := time.Parse(time.RFC3339, r.From)
+		to, err2 := time.Parse(time.RFC3339, r.To)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		// keep the parts outside the range
+		if from.Before(start.Time) {
+			ranges = append(ranges, PeriodRange{From: r.From, To: Bound{Time: minTime(to, start.Time)}.String(), Period: r.Period})
+		}
+		if end.Time.Before(to) {
+			ranges = append(ranges, PeriodRange{From: Bound{Time: maxTime(from, end.Time)}.String(), To: r.To, Period: r.Period})
+		}
+	}
+	if period != s.Period {
+		ranges = append(ranges, PeriodRange{From: start.String(), To: end.String(), Period: period})
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		from1, _ := time.Parse(time.RFC3339, ranges[i].From)
+		from2, _ := time.Parse(time.RFC3339, ranges[j].From)
+		return from1.Before(from2)
+	})
+	s.PeriodRanges = ranges
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// Current returns the start of the current partition, which is based
+// on today for periods and the max value of the column for intervals
+func (s Settings) Current(ctx context.Context, db *sql.DB, table Table) (Bound, error) {
+	if s.Numeric() {
+		maxID, err := table.MaxID(ctx, db, s.Column, "", -1)
+		if err != nil {
+			return Bound{}, err
+		}
+		return s.Round(Bound{Value: maxID}), nil
+	}
+	return s.Round(Bound{Time: time.Now()}), nil
+}
+
+func fetchSettings(ctx context.Context, db *sql.DB, originalTable Table, table Table) (Settings, error) {
+	triggerName := originalTable.TriggerName()
+	triggerComment, err := table.FetchTrigger(ctx, db, triggerName)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	comment := triggerComment
+	if comment == "" {
+		comment, err = table.FetchComment(ctx, db)
+		if err != nil {
+			return Settings{}, err
+		}
+	}
+
+	settings, err := parseSettings(comment)
+	if err != nil {
+		return Settings{}, err
+	}
+	settings.Declarative = triggerComment == ""
+	return settings, nil
+}
+
+type SettingsOptions struct {
+	Table string
+	Set   map[string]string
+}
+
+// settingsTable is the intermediate table until swapped
+func settingsTable(ctx context.Context, db *sql.DB, originalTable Table) (Table, error) {
+	intermediateTable := originalTable.IntermediateTable()
+	exists, err := intermediateTable.Exists(ctx, db)
+	if err != nil {
+		return Table{}, err
+	}
+	if exists {
+		return intermediateTable, nil
+	}
+
+	exists, err = originalTable.Exists(ctx, db)
+	if err != nil {
+		return Table{}, err
+	}
+	if !exists {
+		return Table{}, abort(fmt.Sprintf("Table not found: %s", originalTable.FullName()))
+	}
+	return originalTable, nil
+}
+
+// TableSettings returns the settings for a table, which are on the
+// intermediate table until swapped
+func TableSettings(ctx context.Context, db *sql.DB, name string) (Settings, error) {
+	originalTable := createTable(name)
+	table, err := settingsTable(ctx, db, originalTable)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	settings, err := fetchSettings(ctx, db, originalTable, table)
+	if err != nil {
+		return Settings{}, err
+	}
+	if !settings.Partitioned() {
+		return Settings{}, abort(fmt.Sprintf("No settings found: %s", table.FullName()))
+	}
+	return settings, nil
+}
+
+// UpdateSettings saves changes to settings. This doesn't change
+// existing partitions, so it's mostly useful for fixing settings.
+func UpdateSettings(ctx context.Context, db *sql.DB, opts SettingsOptions) (*Plan, error) {
+	originalTable := createTable(opts.Table)
+	table, err := settingsTable(ctx, db, originalTable)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := fetchSettings(ctx, db, originalTable, table)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Partitioned() {
+		return nil, abort(fmt.Sprintf("No settings found: %s", table.FullName()))
+	}
+
+	keys := make([]string, 0, len(opts.Set))
+	for key := range opts.Set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		err := settings.Set(key, opts.Set[key])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if settings.Period != "" && settings.Interval != 0 {
+		return nil, abort("Can't use a period and an interval")
+	}
+	if settings.NameTemplate != "" && !settings.ValidTemplate(settings.NameTemplate) {
+		return nil, abort("Invalid name template: " + settings.NameTemplate)
+	}
+	if !settings.validTimeZone() {
+		return nil, abort("Period hour requires UTC")
+	}
+	if !settings.validCast() {
+		return nil, abort(fmt.Sprintf("Invalid cast for %s: %s", cmp.Or(settings.Period, "an interval"), settings.Cast))
+	}
+
+	plan := &Plan{}
+	addSaveSettings(plan, table, originalTable.TriggerName(), settings)
+	return plan, nil
+}
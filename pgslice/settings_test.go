@@ -0,0 +1,212 @@
+package pgslice
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSettings(t *testing.T) {
+	settings := Settings{Column: "created:at,utc", Period: "day", Cast: "timestamptz", Default: true, TimeZone: "America/New_York"}
+	parsed, err := parseSettings(settings.Comment())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, settings) {
+		t.Errorf("expected %+v, got %+v", settings, parsed)
+	}
+}
+
+func TestParseSettingsLegacy(t *testing.T) {
+	parsed, err := parseSettings("column:createdAt,interval:1000,cast:bigint,sub_column:UserId,sub_modulus:4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Settings{Column: "createdAt", Interval: 1000, Cast: "bigint", SubColumn: "UserId", SubModulus: 4}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("expected %+v, got %+v", expected, parsed)
+	}
+}
+
+func TestParseSettingsOtherComment(t *testing.T) {
+	parsed, err := parseSettings("Posts by users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Partitioned() {
+		t.Errorf("expected no settings, got %+v", parsed)
+	}
+}
+
+func TestParseBound(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2026, 3, 9, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		settings Settings
+		value    string
+	}{
+		{Settings{Period: "day", Cast: "date", TimeZone: "America/New_York"}, "'2026-03-09'"},
+		{Settings{Period: "day", Cast: "timestamp", TimeZone: "America/New_York"}, "'2026-03-09 00:00:00'"},
+		{Settings{Period: "day", Cast: "timestamptz", TimeZone: "America/New_York"}, "'2026-03-09 04:00:00+00'"},
+		{Settings{Period: "day", Cast: "timestamptz", TimeZone: "America/New_York"}, "'2026-03-09 09:30:00+05:30'"},
+		{Settings{Period: "day", Cast: "epoch", TimeZone: "America/New_York"}, "1773028800"},
+		{Settings{Period: "day", Cast: "epoch_ms", TimeZone: "America/New_York"}, "'1773028800000'"},
+	}
+	for _, tt := range tests {
+		bound, err := tt.settings.ParseBound(tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bound.Time.Equal(expected) || bound.Time.Location().String() != loc.String() {
+			t.Errorf("%s: expected %s, got %s", tt.value, expected, bound.Time)
+		}
+	}
+
+	bound, err := Settings{Interval: 1000, Cast: "bigint"}.ParseBound("-2000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bound.Value != -2000 {
+		t.Errorf("expected -2000, got %d", bound.Value)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		settings Settings
+		bound    string
+		start    string
+		end      string
+	}{
+		{Settings{Column: "createdAt", Period: "month", Cast: "date", Declarative: true}, "FOR VALUES FROM ('2026-01-01') TO ('2027-01-01')", "2026-01-01T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{Settings{Column: "createdAt", Period: "day", Cast: "timestamptz", Declarative: true}, "FOR VALUES FROM ('2026-01-01 00:00:00+00') TO ('2026-01-02 00:00:00+00')", "2026-01-01T00:00:00Z", "2026-01-02T00:00:00Z"},
+		{Settings{Column: "createdAt", Period: "day", Cast: "timestamp"}, `CHECK ((("createdAt" >= '2026-01-01 00:00:00'::timestamp without time zone) AND ("createdAt" < '2026-01-02 00:00:00'::timestamp without time zone)))`, "2026-01-01T00:00:00Z", "2026-01-02T00:00:00Z"},
+		{Settings{Column: "created_at", Period: "day", Cast: "date"}, "CHECK (((created_at >= '2026-01-01'::date) AND (created_at < '2026-01-02'::date))) NOT VALID", "2026-01-01T00:00:00Z", "2026-01-02T00:00:00Z"},
+		{Settings{Column: "createdAt", Period: "day", Cast: "date"}, `CHECK ((("createdAt" IS NOT NULL) AND ("createdAt" >= '2026-01-01'::date) AND ("createdAt" < '2026-01-02'::date)))`, "2026-01-01T00:00:00Z", "2026-01-02T00:00:00Z"},
+		{Settings{Column: "Id", Interval: 1000, Cast: "bigint"}, `CHECK ((("Id" >= 1000) AND ("Id" < 2000)))`, "1000", "2000"},
+		{Settings{Column: "Id", Interval: 1000, Cast: "bigint", Declarative: true}, "FOR VALUES FROM ('3000000000') TO ('3000001000')", "3000000000", "3000001000"},
+	}
+	for _, tt := range tests {
+		start, end, ok, err := tt.settings.ParseRange(tt.bound)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || start.String() != tt.start || end.String() != tt.end {
+			t.Errorf("%s: expected %s to %s, got %s to %s", tt.bound, tt.start, tt.end, start, end)
+		}
+	}
+
+	for _, bound := range []string{"DEFAULT", "FOR VALUES FROM (MINVALUE) TO ('2026-01-01')", `CHECK (("createdAt" IS NOT NULL))`} {
+		_, _, ok, err := Settings{Column: "createdAt", Period: "day", Cast: "date", Declarative: true}.ParseRange(bound)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Errorf("%s: expected no range", bound)
+		}
+	}
+}
+
+func TestSetPeriod(t *testing.T) {
+	settings := Settings{Column: "createdAt", Period: "month", Cast: "date"}
+	day := func(month time.Month, day int) Bound {
+		return Bound{Time: time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)}
+	}
+
+	settings.SetPeriod(day(1, 1), day(3, 1), "day")
+	settings.SetPeriod(day(2, 1), day(3, 1), "month")
+	settings.SetPeriod(day(5, 1), day(6, 1), "day")
+
+	expected := []PeriodRange{
+		{From: "2026-01-01T00:00:00Z", To: "2026-02-01T00:00:00Z", Period: "day"},
+		{From: "2026-05-01T00:00:00Z", To: "2026-06-01T00:00:00Z", Period: "day"},
+	}
+	if !reflect.DeepEqual(settings.PeriodRanges, expected) {
+		t.Errorf("expected %+v, got %+v", expected, settings.PeriodRanges)
+	}
+
+	parsed, err := parseSettings(settings.Comment())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, settings) {
+		t.Errorf("expected %+v, got %+v", settings, parsed)
+	}
+}
+
+func TestValidTimeZone(t *testing.T) {
+	tests := []struct {
+		settings Settings
+		valid    bool
+	}{
+		{Settings{Period: "hour"}, true},
+		{Settings{Period: "hour", TimeZone: "UTC"}, true},
+		{Settings{Period: "hour", TimeZone: "America/New_York"}, false},
+		{Settings{Period: "day", TimeZone: "America/New_York"}, true},
+	}
+
+	for _, tt := range tests {
+		if tt.settings.validTimeZone() != tt.valid {
+			t.Errorf("%+v: expected valid to be %t", tt.settings, tt.valid)
+		}
+	}
+}
+
+func TestSetCast(t *testing.T) {
+	var settings Settings
+	err := settings.Set("cast", "epoch_ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Cast != "epoch_ms" {
+		t.Errorf("expected epoch_ms, got %s", settings.Cast)
+	}
+
+	err = settings.Set("cast", "text")
+	if err == nil || err.Error() != "Invalid cast: text" {
+		t.Errorf("expected invalid cast, got %v", err)
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	var settings Settings
+	err := settings.Set("default", "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !settings.Default {
+		t.Error("expected default")
+	}
+
+	err = settings.Set("default", "yes")
+	if err == nil || err.Error() != "Invalid default: yes" {
+		t.Errorf("expected invalid default, got %v", err)
+	}
+	if !settings.Default {
+		t.Error("expected default to be unchanged")
+	}
+}
+
+func TestValidCast(t *testing.T) {
+	tests := []struct {
+		settings Settings
+		valid    bool
+	}{
+		{Settings{Period: "day", Cast: "date"}, true},
+		{Settings{Period: "hour", Cast: "date"}, false},
+		{Settings{Period: "day", Cast: "bigint"}, false},
+		{Settings{Interval: 1000, Cast: "bigint"}, true},
+		{Settings{Interval: 1000, Cast: "epoch"}, false},
+		{Settings{Strategy: "list", Cast: "text"}, true},
+	}
+
+	for _, tt := range tests {
+		if tt.settings.validCast() != tt.valid {
+			t.Errorf("%+v: expected valid to be %t", tt.settings, tt.valid)
+		}
+	}
+}
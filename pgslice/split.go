@@ -0,0 +1,356 @@
+package pgslice
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+)
+
+type RepartitionOptions struct {
+	Table       string
+	From        string
+	To          string
+	Period      string
+	BatchSize   int
+	Sleep       time.Duration
+	LockTimeout string
+	Drop        bool
+	DryRun      bool
+	Log         io.Writer
+}
+
+// Split replaces the partitions in a range with smaller ones, like days for a month
+func Split(ctx context.Context, db *sql.DB, opts RepartitionOptions) error {
+	return repartition(ctx, db, opts, true)
+}
+
+// Merge replaces the partitions in a range with larger ones, like a month for days
+func Merge(ctx context.Context, db *sql.DB, opts RepartitionOptions) error {
+	return repartition(ctx, db, opts, false)
+}
+
+// repartition creates the new partitions as tables, syncs changes to them
+// from the old partitions with triggers, fills them in batches, and swaps
+// them in with a short lock.
+func repartition(ctx context.Context, db *sql.DB, opts RepartitionOptions, split bool) error {
+	command := "merge"
+	if split {
+		command = "split"
+	}
+
+	table := createTable(opts.Table)
+	triggerName := table.TriggerName()
+
+	if opts.From == "" || opts.To == "" || opts.Period == "" {
+		return abort(fmt.Sprintf("Usage: pgslice %s TABLE --from FROM --to TO --period PERIOD", command))
+	}
+	if !contains(periods, opts.Period) {
+		return abort("Invalid period: " + opts.Period)
+	}
+
+	exists, err := table.Exists(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	settings, err := fetchSettings(ctx, db, table, table)
+	if err != nil {
+		return err
+	}
+	declarative := settings.Declarative
+
+	if !settings.Partitioned() {
+		return abort(fmt.Sprintf("No settings found: %s", table.FullName()))
+	}
+	if !settings.Ranged() || settings.Numeric() {
+		return abort(fmt.Sprintf("Can't %s without a period", command))
+	}
+	if settings.Subpartitioned() {
+		return abort(fmt.Sprintf("Can't %s sub-partitioned tables", command))
+	}
+
+	start, err := settings.ParseBound(opts.From)
+	if err != nil {
+		return abort("Invalid from: " + opts.From)
+	}
+	end, err := settings.ParseBound(opts.To)
+	if err != nil {
+		return abort("Invalid to: " + opts.To)
+	}
+	if !start.Before(end) {
+		return abort("From must be before to")
+	}
+
+	partitions, err := table.PartitionBounds(ctx, db, settings)
+	if err != nil {
+		return err
+	}
+
+	sources := []PartitionRange{}
+	keptPartitions := []PartitionRange{}
+	for _, partition := range partitions {
+		if !rangesOverlap(partition.Start, partition.End, start, end) {
+			keptPartitions = append(keptPartitions, partition)
+			continue
+		}
+		if partition.Start.Before(start) || end.Before(partition.End) {
+			return abort(fmt.Sprintf("Partition extends outside the range: %s", partition.Table.FullName()))
+		}
+		sources = append(sources, partition)
+	}
+
+	if len(sources) == 0 {
+		return abort("No partitions in range")
+	}
+	// sources are sorted by start
+	previousEnd := start
+	for _, source := range sources {
+		if !boundsEqual(source.Start, previousEnd) {
+			return abort(fmt.Sprintf("Missing partition for range starting at %s", previousEnd))
+		}
+		previousEnd = source.End
+	}
+	if !boundsEqual(previousEnd, end) {
+		return abort(fmt.Sprintf("Missing partition for range starting at %s", previousEnd))
+	}
+
+	newSettings := settings
+	newSettings.Period = opts.Period
+	if newSettings.NameTemplate != "" && !newSettings.ValidTemplate(newSettings.NameTemplate) {
+		return abort(fmt.Sprintf("Name template doesn't work with period %s: %s", opts.Period, newSettings.NameTemplate))
+	}
+	if !newSettings.validTimeZone() {
+		return abort("Period hour requires UTC")
+	}
+	if !boundsEqual(newSettings.Round(start), start) {
+		return abort(fmt.Sprintf("From doesn't start a %s", opts.Period))
+	}
+
+	defs := []partitionDef{}
+	for b := start; b.Before(end); b = newSettings.Advance(b, 1) {
+		defs = append(defs, rangePartitionDef(table, newSettings, b))
+	}
+	if !boundsEqual(defs[len(defs)-1].end, end) {
+		return abort(fmt.Sprintf("To doesn't end a %s", opts.Period))
+	}
+
+	// the sources for each new partition
+	defSources := make([][]PartitionRange, len(defs))
+	for i, def := range defs {
+		for _, source := range sources {
+			if rangesOverlap(source.Start, source.End, def.start, def.end) {
+				defSources[i] = append(defSources[i], source)
+			}
+		}
+	}
+
+	if split {
+		if len(defs) <= len(sources) {
+			return abort("Use merge for larger partitions")
+		}
+		for i := range defs {
+			if len(defSources[i]) > 1 {
+				return abort(fmt.Sprintf("Partitions in the range aren't multiples of a %s", opts.Period))
+			}
+		}
+	} else {
+		if len(defs) >= len(sources) {
+			return abort("Use split for smaller partitions")
+		}
+		for _, source := range sources {
+			if source.Start.Before(newSettings.Round(source.Start)) || newSettings.Advance(newSettings.Round(source.Start), 1).Before(source.End) {
+				return abort(fmt.Sprintf("Partition spans multiple %ss: %s", opts.Period, source.Table.FullName()))
+			}
+		}
+	}
+
+	// resume when the new partitions and sync triggers exist
+	existing := []string{}
+	for _, def := range defs {
+		exists, err := def.table.Exists(ctx, db)
+		if err != nil {
+			return err
+		}
+		if exists {
+			existing = append(existing, "table "+def.table.FullName())
+		}
+	}
+	for _, source := range sources {
+		syncing, err := source.Table.TriggerExists(ctx, db, source.Table.SyncTriggerName())
+		if err != nil {
+			return err
+		}
+		if syncing {
+			existing = append(existing, "trigger "+source.Table.SyncTriggerName())
+		}
+	}
+	resume := len(existing) == len(defs)+len(sources)
+	if len(existing) > 0 && !resume {
+		return abort(fmt.Sprintf("Can't resume %s with some new objects missing: %s already exists", command, existing[0]))
+	}
+
+	// partitions have the primary key, indexes, and foreign keys
+	schemaTable := sources[0].Table
+	primaryKey, err := schemaTable.PrimaryKey(ctx, db)
+	if err != nil {
+		return err
+	}
+	if len(primaryKey) == 0 {
+		return abort("No primary key")
+	}
+	indexDefs, err := schemaTable.IndexDefs(ctx, db)
+	if err != nil {
+		return err
+	}
+	fkDefs, err := schemaTable.ForeignKeys(ctx, db)
+	if err != nil {
+		return err
+	}
+	columns, err := table.Columns(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	constraintName := func(def partitionDef) string {
+		return fmt.Sprintf("%s_%s_check", def.table.Name, settings.Column)
+	}
+
+	executeOptions := ExecuteOptions{DryRun: opts.DryRun, Log: opts.Log}
+
+	createPlan := &Plan{}
+	for _, def := range defs {
+		partition := def.table
+		// the constraint lets Postgres attach without scanning the table
+		createPlan.Add("Create table", fmt.Sprintf(`CREATE TABLE %s (
+    LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE,
+    CONSTRAINT %s CHECK (%s)
+);`, quoteTable(partition), quoteTable(table), quoteIdent(constraintName(def)), def.check), newLock(AccessShare, table))
+
+		createPlan.Add("Add primary key", fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", quoteTable(partition), quoteColumns(primaryKey)), newLock(AccessExclusive, partition))
+		for _, indexDef := range indexDefs {
+			createPlan.Add("Copy index", makeIndexDef(indexDef, partition), newLock(Share, partition))
+		}
+		for _, fkDef := range fkDefs {
+			createPlan.Add("Copy foreign key", makeFkDef(fkDef, partition), makeFkLocks(fkDef, partition)...)
+		}
+	}
+
+	// copy changes made during the fill to the new partitions
+	for _, source := range sources {
+		targets := []syncTarget{}
+		for _, def := range defs {
+			if rangesOverlap(source.Start, source.End, def.start, def.end) {
+				targets = append(targets, syncTarget{table: def.table, start: &def.start, end: &def.end})
+			}
+		}
+		addSyncTrigger(createPlan, source.Table, targets, settings, primaryKey, columns)
+	}
+
+	if resume {
+		logSQL(opts.Log, "/* resuming */")
+	} else {
+		err = createPlan.Execute(ctx, db, executeOptions)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, def := range defs {
+		for _, source := range defSources[i] {
+			if opts.DryRun {
+				logSQL(opts.Log, fmt.Sprintf("/* fill %s from %s */", def.table.FullName(), source.Table.FullName()))
+				continue
+			}
+
+			fillOptions := FillOptions{
+				Table:       source.Table.FullName(),
+				SourceTable: source.Table.FullName(),
+				DestTable:   def.table.FullName(),
+				Where:       def.check,
+				BatchSize:   opts.BatchSize,
+				Sleep:       opts.Sleep,
+				Log:         opts.Log,
+			}
+			if resume {
+				checkpoint, err := FillCheckpoint(ctx, db, fillOptions)
+				if err != nil {
+					return err
+				}
+				fillOptions.Resume = checkpoint != nil
+			}
+
+			err := Fill(ctx, db, fillOptions)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	plan := &Plan{}
+
+	serverVersionNum, err := fetchServerVersionNum(ctx, db)
+	if err != nil {
+		return err
+	}
+	if serverVersionNum >= 90300 {
+		plan.Add("Set lock timeout", fmt.Sprintf("SET LOCAL lock_timeout = '%s';", cmp.Or(opts.LockTimeout, defaultLockTimeout)))
+	}
+
+	for _, source := range sources {
+		if declarative {
+			plan.Add("Detach partition", fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s;", quoteTable(table), quoteTable(source.Table)), newLock(AccessExclusive, table), newLock(AccessExclusive, source.Table))
+		} else {
+			plan.Add("Remove partition", fmt.Sprintf("ALTER TABLE %s NO INHERIT %s;", quoteTable(source.Table), quoteTable(table)), newLock(AccessExclusive, source.Table), newLock(ShareUpdateExclusive, table))
+		}
+	}
+
+	// stop syncing in the same transaction
+	for _, source := range sources {
+		addDropSyncTrigger(plan, source.Table)
+	}
+
+	newPartitions := keptPartitions
+	for _, def := range defs {
+		if declarative {
+			plan.Add("Attach partition", fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s %s;", quoteTable(table), quoteTable(def.table), def.values), newLock(AccessExclusive, table), newLock(AccessExclusive, def.table))
+			// the partition bound replaces the constraint
+			plan.Add("Drop check constraint", fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", quoteTable(def.table), quoteIdent(constraintName(def))), newLock(AccessExclusive, def.table))
+		} else {
+			plan.Add("Inherit table", fmt.Sprintf("ALTER TABLE %s INHERIT %s;", quoteTable(def.table), quoteTable(table)), newLock(AccessExclusive, def.table), newLock(ShareUpdateExclusive, table))
+		}
+		newPartitions = append(newPartitions, PartitionRange{Table: def.table, Start: def.start, End: def.end})
+	}
+
+	if !declarative {
+		current, err := settings.Current(ctx, db, table)
+		if err != nil {
+			return err
+		}
+		plan.Add("Update insert trigger function", makeTriggerDef(triggerName, newPartitions, settings, current, table.DefaultPartition()))
+	}
+
+	settings.SetPeriod(start, end, opts.Period)
+	addSaveSettings(plan, table, triggerName, settings)
+
+	if opts.Drop {
+		for _, source := range sources {
+			plan.Add("Drop partition", fmt.Sprintf("DROP TABLE %s;", quoteTable(source.Table)), newLock(AccessExclusive, source.Table))
+		}
+	}
+
+	return plan.Execute(ctx, db, executeOptions)
+}
+
+func rangesOverlap(start1 Bound, end1 Bound, start2 Bound, end2 Bound) bool {
+	return start1.Before(end2) && start2.Before(end1)
+}
+
+func boundsEqual(a Bound, b Bound) bool {
+	return !a.Before(b) && !b.Before(a)
+}
@@ -0,0 +1,198 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+type TableStatus struct {
+	Name   string `json:"name"`
+	Exists bool   `json:"exists"`
+}
+
+type PartitionStatus struct {
+	Name string `json:"name"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+type FillStatus struct {
+	SourceTable  string   `json:"source_table"`
+	DestTable    string   `json:"dest_table"`
+	SourceRows   int64    `json:"source_rows_estimate"`
+	DestRows     int64    `json:"dest_rows_estimate"`
+	Progress     float64  `json:"progress_estimate"`
+	LastKey      []string `json:"checkpoint_last_key,omitempty"`
+	BatchesSaved int      `json:"checkpoint_batches,omitempty"`
+}
+
+type StatusReport struct {
+	Step              string            `json:"step"`
+	Table             TableStatus       `json:"table"`
+	IntermediateTable TableStatus       `json:"intermediate_table"`
+	RetiredTable      TableStatus       `json:"retired_table"`
+	Partitioning      string            `json:"partitioning,omitempty"`
+	Strategy          string            `json:"strategy,omitempty"`
+	Column            string            `json:"column,omitempty"`
+	Period            string            `json:"period,omitempty"`
+	Interval          int64             `json:"interval,omitempty"`
+	Cast              string            `json:"cast,omitempty"`
+	SubColumn         string            `json:"sub_column,omitempty"`
+	SubModulus        int               `json:"sub_modulus,omitempty"`
+	DefaultPartition  bool              `json:"default_partition"`
+	TimeZone          string            `json:"time_zone,omitempty"`
+	NameTemplate      string            `json:"name_template,omitempty"`
+	PartitionSchema   string            `json:"partition_schema,omitempty"`
+	PeriodRanges      []PeriodRange     `json:"period_ranges,omitempty"`
+	Syncing           bool              `json:"syncing"`
+	Partitions        []PartitionStatus `json:"partitions"`
+	FuturePartitions  int               `json:"future_partitions"`
+	Fill              *FillStatus       `json:"fill,omitempty"`
+}
+
+type StatusOptions struct {
+	Table string
+}
+
+func Status(ctx context.Context, db *sql.DB, opts StatusOptions) (*StatusReport, error) {
+	table := createTable(opts.Table)
+	intermediateTable := table.IntermediateTable()
+	retiredTable := table.RetiredTable()
+
+	var err error
+	status := StatusReport{Partitions: []PartitionStatus{}}
+	for _, s := range []struct {
+		status *TableStatus
+		table  Table
+	}{{&status.Table, table}, {&status.IntermediateTable, intermediateTable}, {&status.RetiredTable, retiredTable}} {
+		s.status.Name = s.table.FullName()
+		s.status.Exists, err = s.table.Exists(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the partitioned table is the intermediate table until swapped
+	var partitionedTable Table
+	var sourceTable Table
+	if status.IntermediateTable.Exists {
+		status.Step = "prepped"
+		partitionedTable = intermediateTable
+		sourceTable = table
+	} else if status.RetiredTable.Exists {
+		status.Step = "swapped"
+		partitionedTable = table
+		sourceTable = retiredTable
+	} else if status.Table.Exists {
+		status.Step = "not started"
+		partitionedTable = table
+	} else {
+		return nil, abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	if status.Table.Exists {
+		status.Syncing, err = table.TriggerExists(ctx, db, table.SyncTriggerName())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if status.Table.Exists || status.IntermediateTable.Exists {
+		settings, err := fetchSettings(ctx, db, table, partitionedTable)
+		if err != nil {
+			return nil, err
+		}
+
+		if settings.Partitioned() {
+			// the retired table was dropped after the swap
+			if status.Step == "not started" {
+				status.Step = "partitioned"
+			}
+			if settings.Declarative {
+				status.Partitioning = "declarative"
+			} else {
+				status.Partitioning = "trigger-based"
+			}
+			status.Strategy = "range"
+			if !settings.Ranged() {
+				status.Strategy = settings.Strategy
+			}
+			status.Column = settings.Column
+			status.Period = settings.Period
+			status.Interval = settings.Interval
+			status.Cast = settings.Cast
+			status.SubColumn = settings.SubColumn
+			status.SubModulus = settings.SubModulus
+			status.DefaultPartition = settings.Default
+			status.TimeZone = settings.TimeZone
+			status.NameTemplate = settings.NameTemplate
+			status.PartitionSchema = settings.PartitionSchema
+			status.PeriodRanges = settings.PeriodRanges
+
+			partitions, err := partitionedTable.Partitions(ctx, db)
+			if err != nil {
+				return nil, err
+			}
+			if !settings.Ranged() {
+				sort.Slice(partitions, func(i, j int) bool {
+					return partitions[i].Name < partitions[j].Name
+				})
+				for _, partition := range partitions {
+					status.Partitions = append(status.Partitions, PartitionStatus{Name: partition.FullName()})
+				}
+			} else {
+				ranges, err := partitionedTable.PartitionBounds(ctx, db, settings)
+				if err != nil {
+					return nil, err
+				}
+				current, err := settings.Current(ctx, db, table)
+				if err != nil {
+					return nil, err
+				}
+				ranged := make(map[Table]bool)
+				for _, r := range ranges {
+					ranged[r.Table] = true
+					status.Partitions = append(status.Partitions, PartitionStatus{Name: r.Table.FullName(), From: r.Start.String(), To: r.End.String()})
+					if current.Before(r.Start) {
+						status.FuturePartitions++
+					}
+				}
+				// default partition last
+				for _, partition := range partitions {
+					if !ranged[partition] {
+						status.Partitions = append(status.Partitions, PartitionStatus{Name: partition.FullName()})
+					}
+				}
+			}
+		}
+	}
+
+	if sourceTable != (Table{}) && status.Table.Exists {
+		fill := FillStatus{SourceTable: sourceTable.FullName(), DestTable: partitionedTable.FullName()}
+		fill.SourceRows, err = sourceTable.EstimatedRows(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		fill.DestRows, err = partitionedTable.EstimatedRows(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		if fill.SourceRows > 0 {
+			fill.Progress = min(float64(fill.DestRows)/float64(fill.SourceRows), 1)
+		}
+
+		checkpoint, err := fetchCheckpoint(ctx, db, partitionedTable, sourceTable)
+		if err != nil {
+			return nil, err
+		}
+		if checkpoint != nil {
+			fill.LastKey = checkpoint.LastKey
+			fill.BatchesSaved = checkpoint.BatchCount
+		}
+		status.Fill = &fill
+	}
+
+	return &status, nil
+}
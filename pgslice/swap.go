@@ -0,0 +1,78 @@
+package pgslice
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type SwapOptions struct {
+	Table       string
+	LockTimeout string
+}
+
+func Swap(ctx context.Context, db *sql.DB, opts SwapOptions) (*Plan, error) {
+	table := createTable(opts.Table)
+	intermediateTable := table.IntermediateTable()
+	retiredTable := table.RetiredTable()
+	lockTimeout := cmp.Or(opts.LockTimeout, defaultLockTimeout)
+
+	exists, err := table.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	exists, err = intermediateTable.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", intermediateTable.FullName()))
+	}
+
+	exists, err = retiredTable.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, abort(fmt.Sprintf("Table already exists: %s", retiredTable.FullName()))
+	}
+
+	plan := &Plan{}
+
+	serverVersionNum, err := fetchServerVersionNum(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverVersionNum >= 90300 {
+		plan.Add("Set lock timeout", fmt.Sprintf("SET LOCAL lock_timeout = '%s';", lockTimeout))
+	}
+
+	// stop syncing in the same transaction
+	syncing, err := table.TriggerExists(ctx, db, table.SyncTriggerName())
+	if err != nil {
+		return nil, err
+	}
+	if syncing {
+		addDropSyncTrigger(plan, table)
+	}
+
+	plan.Add("Rename table to retired table", fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", quoteTable(table), quoteNoSchema(retiredTable)), newLock(AccessExclusive, table))
+	plan.Add("Rename intermediate table to table", fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", quoteTable(intermediateTable), quoteNoSchema(table)), newLock(AccessExclusive, intermediateTable))
+
+	sequences, err := table.Sequences(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sequence := range sequences {
+		plan.Add("Update sequence owner", fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s;", quoteIdent(sequence.Name), quoteTable(table), quoteIdent(sequence.Column)))
+	}
+
+	return plan, nil
+}
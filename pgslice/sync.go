@@ -0,0 +1,198 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type SyncOptions struct {
+	Table string
+}
+
+func Sync(ctx context.Context, db *sql.DB, opts SyncOptions) (*Plan, error) {
+	table := createTable(opts.Table)
+	intermediateTable := table.IntermediateTable()
+	syncTriggerName := table.SyncTriggerName()
+
+	exists, err := table.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	exists, err = intermediateTable.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", intermediateTable.FullName()))
+	}
+
+	exists, err = table.TriggerExists(ctx, db, syncTriggerName)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, abort(fmt.Sprintf("Trigger already exists: %s", syncTriggerName))
+	}
+
+	primaryKey, err := table.PrimaryKey(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if len(primaryKey) == 0 {
+		return nil, abort("No primary key")
+	}
+
+	settings, err := fetchSettings(ctx, db, table, intermediateTable)
+	if err != nil {
+		return nil, err
+	}
+
+	// writes to the table would fail without a partition for the row
+	if settings.Partitioned() {
+		partitions, err := intermediateTable.Partitions(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		if len(partitions) == 0 {
+			return nil, abort("Add partitions before syncing")
+		}
+	}
+
+	partitions, err := intermediateTable.PartitionBounds(ctx, db, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := table.Columns(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	addSyncTrigger(plan, table, intermediateSyncTargets(intermediateTable, settings, partitions), settings, primaryKey, columns)
+	return plan, nil
+}
+
+// syncTarget is a table to copy changes to, with the range of rows
+// to copy, or nil bounds for all rows
+type syncTarget struct {
+	table Table
+	start *Bound
+	end   *Bound
+}
+
+func (t syncTarget) condition(settings Settings, record string) string {
+	if t.start == nil {
+		return ""
+	}
+	field := record + "." + quoteIdent(settings.Column)
+	return fmt.Sprintf("%s >= %s AND %s < %s", field, settings.SQL(*t.start, true), field, settings.SQL(*t.end, true))
+}
+
+// intermediateSyncTargets only copies rows that fit in a partition, with
+// a target for each run of adjacent partitions, since attached partitions
+// can leave gaps
+func intermediateSyncTargets(intermediateTable Table, settings Settings, partitions []PartitionRange) []syncTarget {
+	if !settings.Partitioned() || !settings.Ranged() || settings.Default || len(partitions) == 0 {
+		return []syncTarget{{table: intermediateTable}}
+	}
+
+	partitions = slices.Clone(partitions)
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].Start.Before(partitions[j].Start)
+	})
+
+	targets := []syncTarget{}
+	for _, partition := range partitions {
+		start := partition.Start
+		end := partition.End
+		if len(targets) > 0 && !targets[len(targets)-1].end.Before(start) {
+			last := &targets[len(targets)-1]
+			if last.end.Before(end) {
+				last.end = &end
+			}
+			continue
+		}
+		targets = append(targets, syncTarget{table: intermediateTable, start: &start, end: &end})
+	}
+	return targets
+}
+
+// addSyncTrigger adds steps to copy changes from the table to the targets
+func addSyncTrigger(plan *Plan, table Table, targets []syncTarget, settings Settings, primaryKey []string, columns []string) {
+	syncTriggerName := table.SyncTriggerName()
+
+	plan.Add("Create sync trigger function", syncFunctionDef(syncTriggerName, targets, settings, primaryKey, columns))
+
+	plan.Add("Create sync trigger", fmt.Sprintf(`CREATE TRIGGER %s
+    AFTER INSERT OR UPDATE OR DELETE ON %s
+    FOR EACH ROW EXECUTE PROCEDURE %s();`, quoteIdent(syncTriggerName), quoteTable(table), quoteIdent(syncTriggerName)), newLock(ShareRowExclusive, table))
+}
+
+// syncFunctionDef deletes the old row and inserts the new row in the
+// target for its range. Columns are listed since targets can have
+// a different column order, like attached tables.
+func syncFunctionDef(functionName string, targets []syncTarget, settings Settings, primaryKey []string, columns []string) string {
+	oldConditions := make([]string, len(primaryKey))
+	for i, k := range primaryKey {
+		oldConditions[i] = fmt.Sprintf("%s = OLD.%s", quoteIdent(k), quoteIdent(k))
+	}
+
+	deletes := syncStatements(targets, settings, "OLD", func(t Table) string {
+		return fmt.Sprintf("DELETE FROM %s WHERE %s;", quoteTable(t), strings.Join(oldConditions, " AND "))
+	})
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		values[i] = "NEW." + quoteIdent(c)
+	}
+	inserts := syncStatements(targets, settings, "NEW", func(t Table) string {
+		return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", quoteTable(t), quoteColumns(columns), strings.Join(values, ", "))
+	})
+
+	// delete and insert instead of upsert since partitioned tables
+	// can't have a unique index without the partition column
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s()
+    RETURNS trigger AS $$
+    BEGIN
+        IF TG_OP = 'UPDATE' OR TG_OP = 'DELETE' THEN
+%s
+        END IF;
+        IF TG_OP = 'INSERT' OR TG_OP = 'UPDATE' THEN
+%s
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;`, quoteIdent(functionName), deletes, inserts)
+}
+
+// syncStatements runs a statement for the target whose range contains the record
+func syncStatements(targets []syncTarget, settings Settings, record string, statement func(Table) string) string {
+	if len(targets) == 1 && targets[0].start == nil {
+		return "            " + statement(targets[0].table)
+	}
+
+	var sb strings.Builder
+	for i, target := range targets {
+		keyword := "ELSIF"
+		if i == 0 {
+			keyword = "IF"
+		}
+		fmt.Fprintf(&sb, "            %s %s THEN\n                %s\n", keyword, target.condition(settings, record), statement(target.table))
+	}
+	sb.WriteString("            END IF;")
+	return sb.String()
+}
+
+func addDropSyncTrigger(plan *Plan, table Table) {
+	syncTriggerName := table.SyncTriggerName()
+	plan.Add("Drop sync trigger", fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;", quoteIdent(syncTriggerName), quoteTable(table)), newLock(AccessExclusive, table))
+	plan.Add("Drop sync trigger function", fmt.Sprintf("DROP FUNCTION IF EXISTS %s();", quoteIdent(syncTriggerName)))
+}
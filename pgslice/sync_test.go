@@ -0,0 +1,63 @@
+package pgslice
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncFunctionDef(t *testing.T) {
+	settings := Settings{Column: "createdAt", Period: "day", Cast: "date"}
+	day := func(d int) *Bound {
+		return &Bound{Time: time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC)}
+	}
+
+	tests := []struct {
+		name    string
+		targets []syncTarget
+	}{
+		{"all_rows", []syncTarget{{table: createTable("Posts_intermediate")}}},
+		{"range", []syncTarget{{table: createTable("Posts_intermediate"), start: day(1), end: day(4)}}},
+		{"targets", []syncTarget{
+			{table: createTable("Posts_20260101"), start: day(1), end: day(2)},
+			{table: createTable("Posts_20260102"), start: day(2), end: day(3)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := syncFunctionDef("Posts_sync_trigger", tt.targets, settings, []string{"Id"}, []string{"Id", "createdAt"}) + "\n"
+			assertGolden(t, filepath.Join("testdata", "sync", tt.name+".sql"), actual)
+		})
+	}
+}
+
+func TestIntermediateSyncTargets(t *testing.T) {
+	settings := Settings{Column: "createdAt", Period: "day", Cast: "date", Declarative: true}
+	table := createTable("Posts_intermediate")
+	day := func(d int) Bound {
+		return Bound{Time: time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC)}
+	}
+	partition := func(d int) PartitionRange {
+		return PartitionRange{Table: createTable(fmt.Sprintf("Posts_202601%02d", d)), Start: day(d), End: day(d + 1)}
+	}
+
+	// attached partitions can leave gaps
+	targets := intermediateSyncTargets(table, settings, []PartitionRange{partition(5), partition(1), partition(2)})
+	expected := [][2]Bound{{day(1), day(3)}, {day(5), day(6)}}
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %d targets, got %d", len(expected), len(targets))
+	}
+	for i, target := range targets {
+		if target.table != table || target.start.String() != expected[i][0].String() || target.end.String() != expected[i][1].String() {
+			t.Errorf("expected %s to %s, got %s to %s", expected[i][0], expected[i][1], target.start, target.end)
+		}
+	}
+
+	settings.Default = true
+	targets = intermediateSyncTargets(table, settings, []PartitionRange{partition(1)})
+	if len(targets) != 1 || targets[0].start != nil {
+		t.Errorf("expected all rows with a default partition")
+	}
+}
@@ -0,0 +1,596 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Table struct {
+	Schema string
+	Name   string
+}
+
+type Sequence struct {
+	Name   string
+	Column string
+}
+
+func (t Table) IntermediateTable() Table {
+	return Table{Schema: t.Schema, Name: t.Name + "_intermediate"}
+}
+
+func (t Table) RetiredTable() Table {
+	return Table{Schema: t.Schema, Name: t.Name + "_retired"}
+}
+
+// DefaultPartition is named after the original table so it keeps its name after swap
+func (t Table) DefaultPartition() Table {
+	return Table{Schema: t.Schema, Name: t.Name + "_default"}
+}
+
+func (t Table) TriggerName() string {
+	return t.Name + "_insert_trigger"
+}
+
+func (t Table) SyncTriggerName() string {
+	return t.Name + "_sync_trigger"
+}
+
+func (t Table) FullName() string {
+	return strings.Join([]string{t.Schema, t.Name}, ".")
+}
+
+func (t Table) Exists(ctx context.Context, db *sql.DB) (bool, error) {
+	tables, err := t.ExistingTables(ctx, db, t.Name)
+	if err != nil {
+		return false, err
+	}
+	return len(tables) > 0, nil
+}
+
+func (t Table) Sequences(ctx context.Context, db *sql.DB) ([]Sequence, error) {
+	query := `
+SELECT
+  s.relname as name,
+  a.attname as column
+FROM pg_class s
+  JOIN pg_depend d ON d.objid = s.oid
+  JOIN pg_class t ON d.objid = s.oid AND d.refobjid = t.oid
+  JOIN pg_attribute a ON (d.refobjid, d.refobjsubid) = (a.attrelid, a.attnum)
+  JOIN pg_namespace n ON n.oid = s.relnamespace
+WHERE s.relkind = 'S'
+  AND n.nspname = $1
+  AND t.relname = $2
+  `
+	rows, err := db.QueryContext(ctx, query, t.Schema, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sequences := []Sequence{}
+	for rows.Next() {
+		var s Sequence
+		err := rows.Scan(&s.Name, &s.Column)
+		if err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, s)
+	}
+	return sequences, nil
+}
+
+func (t Table) ExistingTables(ctx context.Context, db *sql.DB, like string) ([]Table, error) {
+	query := "SELECT schemaname AS schema, tablename as name FROM pg_catalog.pg_tables WHERE schemaname = $1 AND tablename LIKE $2 ORDER BY 1, 2"
+	rows, err := db.QueryContext(ctx, query, t.Schema, like)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []Table{}
+	for rows.Next() {
+		var t Table
+		err := rows.Scan(&t.Schema, &t.Name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// PartitionRange is a range partition and its bounds
+type PartitionRange struct {
+	Table Table
+	Start Bound
+	End   Bound
+}
+
+// PartitionBounds returns range partitions sorted by start. Bounds come from
+// the catalog for declarative partitioning and CHECK constraints for inheritance,
+// so they don't depend on names and work for partitions created outside pgslice.
+// Other partitions, like the default or unbounded ranges, are skipped.
+func (t Table) PartitionBounds(ctx context.Context, db *sql.DB, settings Settings) ([]PartitionRange, error) {
+	expr := "pg_get_expr(child.relpartbound, child.oid)"
+	if !settings.Declarative {
+		// relpartbound requires Postgres 10+
+		expr = "pg_get_constraintdef(pg_constraint.oid)"
+	}
+
+	query := fmt.Sprintf(`
+SELECT
+  nmsp_child.nspname  AS schema,
+  child.relname       AS name,
+  COALESCE(%s, '') AS bound
+FROM pg_inherits
+  JOIN pg_class parent            ON pg_inherits.inhparent = parent.oid
+  JOIN pg_class child             ON pg_inherits.inhrelid   = child.oid
+  JOIN pg_namespace nmsp_parent   ON nmsp_parent.oid  = parent.relnamespace
+  JOIN pg_namespace nmsp_child    ON nmsp_child.oid   = child.relnamespace
+  LEFT JOIN pg_constraint         ON pg_constraint.conrelid = child.oid AND pg_constraint.contype = 'c'
+WHERE
+  nmsp_parent.nspname = $1 AND
+  parent.relname = $2
+  `, expr)
+	rows, err := db.QueryContext(ctx, query, t.Schema, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ranges := []PartitionRange{}
+	seen := make(map[Table]bool)
+	for rows.Next() {
+		var partition Table
+		var bound string
+		err := rows.Scan(&partition.Schema, &partition.Name, &bound)
+		if err != nil {
+			return nil, err
+		}
+		if seen[partition] {
+			continue
+		}
+
+		start, end, ok, err := settings.ParseRange(bound)
+		if err != nil {
+			return nil, abort(fmt.Sprintf("Invalid bound for %s: %s", partition.FullName(), err))
+		}
+		if !ok {
+			continue
+		}
+		ranges = append(ranges, PartitionRange{Table: partition, Start: start, End: end})
+		seen[partition] = true
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Start.Before(ranges[j].Start)
+	})
+	return ranges, nil
+}
+
+func (t Table) Partitions(ctx context.Context, db *sql.DB) ([]Table, error) {
+	query := `
+SELECT
+  nmsp_child.nspname  AS schema,
+  child.relname       AS name
+FROM pg_inherits
+  JOIN pg_class parent            ON pg_inherits.inhparent = parent.oid
+  JOIN pg_class child             ON pg_inherits.inhrelid   = child.oid
+  JOIN pg_namespace nmsp_parent   ON nmsp_parent.oid  = parent.relnamespace
+  JOIN pg_namespace nmsp_child    ON nmsp_child.oid   = child.relnamespace
+WHERE
+  nmsp_parent.nspname = $1 AND
+  parent.relname = $2
+  `
+	rows, err := db.QueryContext(ctx, query, t.Schema, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []Table{}
+	for rows.Next() {
+		var t Table
+		err := rows.Scan(&t.Schema, &t.Name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// AllPartitions returns partitions at every level for sub-partitioning
+func (t Table) AllPartitions(ctx context.Context, db *sql.DB) ([]Table, error) {
+	return t.partitionTree(ctx, db, false)
+}
+
+// LeafPartitions returns partitions that store rows
+func (t Table) LeafPartitions(ctx context.Context, db *sql.DB) ([]Table, error) {
+	return t.partitionTree(ctx, db, true)
+}
+
+func (t Table) partitionTree(ctx context.Context, db *sql.DB, leaves bool) ([]Table, error) {
+	query := `
+WITH RECURSIVE tree AS (
+  SELECT pg_inherits.inhrelid AS oid
+  FROM pg_inherits
+    JOIN pg_class parent            ON pg_inherits.inhparent = parent.oid
+    JOIN pg_namespace nmsp_parent   ON nmsp_parent.oid  = parent.relnamespace
+  WHERE
+    nmsp_parent.nspname = $1 AND
+    parent.relname = $2
+  UNION ALL
+  SELECT pg_inherits.inhrelid
+  FROM pg_inherits
+    JOIN tree ON pg_inherits.inhparent = tree.oid
+)
+SELECT
+  nmsp_child.nspname  AS schema,
+  child.relname       AS name
+FROM tree
+  JOIN pg_class child             ON child.oid = tree.oid
+  JOIN pg_namespace nmsp_child    ON nmsp_child.oid   = child.relnamespace
+WHERE
+  NOT $3 OR NOT EXISTS (SELECT 1 FROM pg_inherits WHERE pg_inherits.inhparent = child.oid)
+ORDER BY 1, 2
+  `
+	rows, err := db.QueryContext(ctx, query, t.Schema, t.Name, leaves)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []Table{}
+	for rows.Next() {
+		var t Table
+		err := rows.Scan(&t.Schema, &t.Name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// HashModuli returns the distinct moduli of hash partitions at every level
+func (t Table) HashModuli(ctx context.Context, db *sql.DB) ([]int, error) {
+	query := `
+WITH RECURSIVE tree AS (
+  SELECT pg_inherits.inhrelid AS oid
+  FROM pg_inherits
+    JOIN pg_class parent            ON pg_inherits.inhparent = parent.oid
+    JOIN pg_namespace nmsp_parent   ON nmsp_parent.oid  = parent.relnamespace
+  WHERE
+    nmsp_parent.nspname = $1 AND
+    parent.relname = $2
+  UNION ALL
+  SELECT pg_inherits.inhrelid
+  FROM pg_inherits
+    JOIN tree ON pg_inherits.inhparent = tree.oid
+)
+SELECT DISTINCT
+  substring(pg_get_expr(child.relpartbound, child.oid) FROM 'modulus (\d+)')::int AS modulus
+FROM tree
+  JOIN pg_class child             ON child.oid = tree.oid
+WHERE
+  pg_get_expr(child.relpartbound, child.oid) LIKE '%modulus%'
+ORDER BY 1
+  `
+	rows, err := db.QueryContext(ctx, query, t.Schema, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	moduli := []int{}
+	for rows.Next() {
+		var modulus int
+		err := rows.Scan(&modulus)
+		if err != nil {
+			return nil, err
+		}
+		moduli = append(moduli, modulus)
+	}
+	return moduli, nil
+}
+
+func (t Table) Columns(ctx context.Context, db *sql.DB) ([]string, error) {
+	query := "SELECT column_name FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2"
+	rows, err := db.QueryContext(ctx, query, t.Schema, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var k string
+		err := rows.Scan(&k)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (t Table) ForeignKeys(ctx context.Context, db *sql.DB) ([]string, error) {
+	query := "SELECT pg_get_constraintdef(oid) FROM pg_constraint WHERE conrelid = $1::regclass AND contype ='f'"
+	rows, err := db.QueryContext(ctx, query, quoteTable(t))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var k string
+		err := rows.Scan(&k)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// MaxID returns 0 for an empty table
+func (t Table) MaxID(ctx context.Context, db *sql.DB, primaryKey string, where string, below int64) (int64, error) {
+	query := fmt.Sprintf("SELECT MAX(%s) FROM %s", quoteIdent(primaryKey), quoteTable(t))
+
+	conditions := []string{}
+	if below != -1 {
+		conditions = append(conditions, fmt.Sprintf("%s <= %d", quoteIdent(primaryKey), below))
+	}
+	if where != "" {
+		conditions = append(conditions, where)
+	}
+	if len(conditions) > 0 {
+		query = query + " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var max sql.NullInt64
+	err := db.QueryRowContext(ctx, query).Scan(&max)
+	return max.Int64, err
+}
+
+// MinID returns 1 for an empty table
+func (t Table) MinID(ctx context.Context, db *sql.DB, primaryKey string, conditions []string) (int64, error) {
+	query := fmt.Sprintf("SELECT MIN(%s) FROM %s", quoteIdent(primaryKey), quoteTable(t))
+
+	if len(conditions) > 0 {
+		query = query + " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var min sql.NullInt64
+	err := db.QueryRowContext(ctx, query).Scan(&min)
+	if err != nil {
+		return 0, err
+	}
+	if !min.Valid {
+		return 1, nil
+	}
+	return min.Int64, nil
+}
+
+func (t Table) MaxKey(ctx context.Context, db *sql.DB, key []string, conditions []string) ([]string, error) {
+	return t.fetchKey(ctx, db, key, conditions, "DESC", 0)
+}
+
+func (t Table) NextKey(ctx context.Context, db *sql.DB, key []string, conditions []string, offset int) ([]string, error) {
+	return t.fetchKey(ctx, db, key, conditions, "ASC", offset)
+}
+
+func (t Table) fetchKey(ctx context.Context, db *sql.DB, key []string, conditions []string, direction string, offset int) ([]string, error) {
+	selects := make([]string, len(key))
+	orders := make([]string, len(key))
+	for i, k := range key {
+		selects[i] = quoteIdent(k) + "::text"
+		orders[i] = quoteIdent(k) + " " + direction
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), quoteTable(t))
+	if len(conditions) > 0 {
+		query = query + " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query = query + fmt.Sprintf(" ORDER BY %s LIMIT 1 OFFSET %d", strings.Join(orders, ", "), offset)
+
+	values := make([]string, len(key))
+	dest := make([]any, len(key))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err := db.QueryRowContext(ctx, query).Scan(dest...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return values, nil
+}
+
+func (t Table) ColumnDataType(ctx context.Context, db *sql.DB, column string) (string, error) {
+	var dataType string
+	err := db.QueryRowContext(ctx, "SELECT data_type FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 AND column_name = $3", t.Schema, t.Name, column).Scan(&dataType)
+	return dataType, err
+}
+
+// ColumnCast returns how to write bounds for a column. Integer columns
+// are epoch seconds or milliseconds, from the cast if given, or else
+// guessed from existing values.
+func (t Table) ColumnCast(ctx context.Context, db *sql.DB, column string, cast string) (string, error) {
+	dataType, err := t.ColumnDataType(ctx, db, column)
+	if err != nil {
+		return "", err
+	}
+
+	var columnCast string
+	switch dataType {
+	case "timestamp with time zone":
+		columnCast = "timestamptz"
+	case "timestamp without time zone":
+		columnCast = "timestamp"
+	case "date":
+		columnCast = "date"
+	case "integer", "bigint":
+		if cast == "epoch" || cast == "epoch_ms" {
+			return cast, nil
+		}
+		if cast != "" {
+			break
+		}
+
+		var maxValue sql.NullInt64
+		err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(%s) FROM %s", quoteIdent(column), quoteTable(t))).Scan(&maxValue)
+		if err != nil {
+			return "", err
+		}
+		if !maxValue.Valid {
+			return "", abort(fmt.Sprintf("No values to tell if %s is seconds or milliseconds. Use --cast epoch or --cast epoch_ms", column))
+		}
+		// year 5138 in seconds, but 1973 in milliseconds
+		if maxValue.Int64 > 100_000_000_000 {
+			return "epoch_ms", nil
+		}
+		return "epoch", nil
+	default:
+		return "", abort(fmt.Sprintf("Column must be a date, timestamp, or integer: %s", column))
+	}
+
+	if cast != "" && cast != columnCast {
+		return "", abort(fmt.Sprintf("Invalid cast for %s: %s", column, cast))
+	}
+	return columnCast, nil
+}
+
+func (t Table) PrimaryKey(ctx context.Context, db *sql.DB) ([]string, error) {
+	query := `
+    SELECT
+      pg_attribute.attname
+    FROM
+      pg_index, pg_class, pg_attribute, pg_namespace
+    WHERE
+      nspname = $1 AND
+      relname = $2 AND
+      indrelid = pg_class.oid AND
+      pg_class.relnamespace = pg_namespace.oid AND
+      pg_attribute.attrelid = pg_class.oid AND
+      pg_attribute.attnum = any(pg_index.indkey) AND
+      indisprimary
+    ORDER BY
+      array_position(pg_index.indkey::int2[], pg_attribute.attnum)
+  `
+	rows, err := db.QueryContext(ctx, query, t.Schema, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var k string
+		err := rows.Scan(&k)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (t Table) IndexDefs(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT pg_get_indexdef(indexrelid) FROM pg_index WHERE indrelid = $1::regclass AND indisprimary = 'f'", quoteTable(t))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	defs := []string{}
+	for rows.Next() {
+		var k string
+		err := rows.Scan(&k)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, k)
+	}
+	return defs, nil
+}
+
+func (t Table) FetchComment(ctx context.Context, db *sql.DB) (string, error) {
+	var comment string
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(obj_description($1::regclass), '') AS comment", quoteTable(t)).Scan(&comment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return comment, nil
+}
+
+func (t Table) FetchTrigger(ctx context.Context, db *sql.DB, triggerName string) (string, error) {
+	var trigger string
+	err := db.QueryRowContext(ctx, "SELECT obj_description(oid, 'pg_trigger') AS comment FROM pg_trigger WHERE tgname = $1 AND tgrelid = $2::regclass", triggerName, quoteTable(t)).Scan(&trigger)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return trigger, nil
+}
+
+func (t Table) TriggerExists(ctx context.Context, db *sql.DB, triggerName string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = $1 AND tgrelid = $2::regclass)", triggerName, quoteTable(t)).Scan(&exists)
+	return exists, err
+}
+
+func (t Table) ConstraintExists(ctx context.Context, db *sql.DB, constraintName string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = $1 AND conrelid = $2::regclass)", constraintName, quoteTable(t)).Scan(&exists)
+	return exists, err
+}
+
+// ColumnNotNull returns true if a column is NOT NULL or has a validated
+// CHECK constraint for IS NOT NULL
+func (t Table) ColumnNotNull(ctx context.Context, db *sql.DB, column string) (bool, error) {
+	query := `
+SELECT
+  EXISTS (SELECT 1 FROM pg_attribute WHERE attrelid = $1::regclass AND attname = $2 AND attnotnull) OR
+  EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = $1::regclass AND contype = 'c' AND convalidated AND pg_get_constraintdef(oid) LIKE '%' || quote_ident($2) || ' IS NOT NULL%')
+  `
+	var notNull bool
+	err := db.QueryRowContext(ctx, query, quoteTable(t), column).Scan(&notNull)
+	return notNull, err
+}
+
+// EstimatedRows uses planner statistics, including all levels of
+// children, and skips partitioned tables since they don't store rows
+func (t Table) EstimatedRows(ctx context.Context, db *sql.DB) (int64, error) {
+	query := `
+WITH RECURSIVE tree AS (
+  SELECT $1::regclass::oid AS oid
+  UNION ALL
+  SELECT pg_inherits.inhrelid
+  FROM pg_inherits
+    JOIN tree ON pg_inherits.inhparent = tree.oid
+)
+SELECT
+  COALESCE(SUM(GREATEST(reltuples, 0)), 0)::bigint
+FROM tree
+  JOIN pg_class ON pg_class.oid = tree.oid
+WHERE
+  pg_class.relkind <> 'p'
+  `
+	var rows int64
+	err := db.QueryRowContext(ctx, query, quoteTable(t)).Scan(&rows)
+	return rows, err
+}
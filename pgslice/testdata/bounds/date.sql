@@ -0,0 +1,27 @@
+-- Posts_20260307
+FOR VALUES FROM ('2026-03-07') TO ('2026-03-08')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-03-07'::date AND "createdAt" < '2026-03-08'::date)
+
+-- Posts_20260308
+FOR VALUES FROM ('2026-03-08') TO ('2026-03-09')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-03-08'::date AND "createdAt" < '2026-03-09'::date)
+
+-- Posts_20260309
+FOR VALUES FROM ('2026-03-09') TO ('2026-03-10')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-03-09'::date AND "createdAt" < '2026-03-10'::date)
+
+CREATE OR REPLACE FUNCTION "Posts_insert_trigger"()
+    RETURNS trigger AS $$
+    BEGIN
+        IF (NEW."createdAt" >= '2026-03-08'::date AND NEW."createdAt" < '2026-03-09'::date) THEN
+            INSERT INTO "public"."Posts_20260308" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= '2026-03-09'::date AND NEW."createdAt" < '2026-03-10'::date) THEN
+            INSERT INTO "public"."Posts_20260309" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= '2026-03-07'::date AND NEW."createdAt" < '2026-03-08'::date) THEN
+            INSERT INTO "public"."Posts_20260307" VALUES (NEW.*);
+        ELSE
+            RAISE EXCEPTION 'Date out of range. Ensure partitions are created.';
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
@@ -0,0 +1,27 @@
+-- Posts_20260307
+FOR VALUES FROM (1772841600) TO (1772928000)
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= 1772841600 AND "createdAt" < 1772928000)
+
+-- Posts_20260308
+FOR VALUES FROM (1772928000) TO (1773014400)
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= 1772928000 AND "createdAt" < 1773014400)
+
+-- Posts_20260309
+FOR VALUES FROM (1773014400) TO (1773100800)
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= 1773014400 AND "createdAt" < 1773100800)
+
+CREATE OR REPLACE FUNCTION "Posts_insert_trigger"()
+    RETURNS trigger AS $$
+    BEGIN
+        IF (NEW."createdAt" >= 1772928000 AND NEW."createdAt" < 1773014400) THEN
+            INSERT INTO "public"."Posts_20260308" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= 1773014400 AND NEW."createdAt" < 1773100800) THEN
+            INSERT INTO "public"."Posts_20260309" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= 1772841600 AND NEW."createdAt" < 1772928000) THEN
+            INSERT INTO "public"."Posts_20260307" VALUES (NEW.*);
+        ELSE
+            RAISE EXCEPTION 'Date out of range. Ensure partitions are created.';
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
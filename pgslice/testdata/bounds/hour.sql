@@ -0,0 +1,27 @@
+-- Posts_2026110105
+FOR VALUES FROM ('2026-11-01 05:00:00 UTC') TO ('2026-11-01 06:00:00 UTC')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-11-01 05:00:00 UTC'::timestamptz AND "createdAt" < '2026-11-01 06:00:00 UTC'::timestamptz)
+
+-- Posts_2026110106
+FOR VALUES FROM ('2026-11-01 06:00:00 UTC') TO ('2026-11-01 07:00:00 UTC')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-11-01 06:00:00 UTC'::timestamptz AND "createdAt" < '2026-11-01 07:00:00 UTC'::timestamptz)
+
+-- Posts_2026110107
+FOR VALUES FROM ('2026-11-01 07:00:00 UTC') TO ('2026-11-01 08:00:00 UTC')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-11-01 07:00:00 UTC'::timestamptz AND "createdAt" < '2026-11-01 08:00:00 UTC'::timestamptz)
+
+CREATE OR REPLACE FUNCTION "Posts_insert_trigger"()
+    RETURNS trigger AS $$
+    BEGIN
+        IF (NEW."createdAt" >= '2026-11-01 06:00:00 UTC'::timestamptz AND NEW."createdAt" < '2026-11-01 07:00:00 UTC'::timestamptz) THEN
+            INSERT INTO "public"."Posts_2026110106" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= '2026-11-01 07:00:00 UTC'::timestamptz AND NEW."createdAt" < '2026-11-01 08:00:00 UTC'::timestamptz) THEN
+            INSERT INTO "public"."Posts_2026110107" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= '2026-11-01 05:00:00 UTC'::timestamptz AND NEW."createdAt" < '2026-11-01 06:00:00 UTC'::timestamptz) THEN
+            INSERT INTO "public"."Posts_2026110105" VALUES (NEW.*);
+        ELSE
+            RAISE EXCEPTION 'Date out of range. Ensure partitions are created.';
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
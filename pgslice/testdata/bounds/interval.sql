@@ -0,0 +1,27 @@
+-- Posts_1000
+FOR VALUES FROM (1000) TO (2000)
+CHECK ("Id" IS NOT NULL AND "Id" >= 1000 AND "Id" < 2000)
+
+-- Posts_2000
+FOR VALUES FROM (2000) TO (3000)
+CHECK ("Id" IS NOT NULL AND "Id" >= 2000 AND "Id" < 3000)
+
+-- Posts_3000
+FOR VALUES FROM (3000) TO (4000)
+CHECK ("Id" IS NOT NULL AND "Id" >= 3000 AND "Id" < 4000)
+
+CREATE OR REPLACE FUNCTION "Posts_insert_trigger"()
+    RETURNS trigger AS $$
+    BEGIN
+        IF (NEW."Id" >= 2000 AND NEW."Id" < 3000) THEN
+            INSERT INTO "public"."Posts_2000" VALUES (NEW.*);
+        ELSIF (NEW."Id" >= 3000 AND NEW."Id" < 4000) THEN
+            INSERT INTO "public"."Posts_3000" VALUES (NEW.*);
+        ELSIF (NEW."Id" >= 1000 AND NEW."Id" < 2000) THEN
+            INSERT INTO "public"."Posts_1000" VALUES (NEW.*);
+        ELSE
+            RAISE EXCEPTION 'Value out of range. Ensure partitions are created.';
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
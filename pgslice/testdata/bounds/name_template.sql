@@ -0,0 +1,27 @@
+-- Posts_p2026_02
+FOR VALUES FROM ('2026-02-01') TO ('2026-03-01')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-02-01'::date AND "createdAt" < '2026-03-01'::date)
+
+-- Posts_p2026_03
+FOR VALUES FROM ('2026-03-01') TO ('2026-04-01')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-03-01'::date AND "createdAt" < '2026-04-01'::date)
+
+-- Posts_p2026_04
+FOR VALUES FROM ('2026-04-01') TO ('2026-05-01')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-04-01'::date AND "createdAt" < '2026-05-01'::date)
+
+CREATE OR REPLACE FUNCTION "Posts_insert_trigger"()
+    RETURNS trigger AS $$
+    BEGIN
+        IF (NEW."createdAt" >= '2026-03-01'::date AND NEW."createdAt" < '2026-04-01'::date) THEN
+            INSERT INTO "archive"."Posts_p2026_03" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= '2026-04-01'::date AND NEW."createdAt" < '2026-05-01'::date) THEN
+            INSERT INTO "archive"."Posts_p2026_04" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= '2026-02-01'::date AND NEW."createdAt" < '2026-03-01'::date) THEN
+            INSERT INTO "archive"."Posts_p2026_02" VALUES (NEW.*);
+        ELSE
+            RAISE EXCEPTION 'Date out of range. Ensure partitions are created.';
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
@@ -0,0 +1,27 @@
+-- Posts_20260307
+FOR VALUES FROM ('2026-03-07 00:00:00 UTC') TO ('2026-03-08 00:00:00 UTC')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-03-07 00:00:00 UTC'::timestamptz AND "createdAt" < '2026-03-08 00:00:00 UTC'::timestamptz)
+
+-- Posts_20260308
+FOR VALUES FROM ('2026-03-08 00:00:00 UTC') TO ('2026-03-09 00:00:00 UTC')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-03-08 00:00:00 UTC'::timestamptz AND "createdAt" < '2026-03-09 00:00:00 UTC'::timestamptz)
+
+-- Posts_20260309
+FOR VALUES FROM ('2026-03-09 00:00:00 UTC') TO ('2026-03-10 00:00:00 UTC')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-03-09 00:00:00 UTC'::timestamptz AND "createdAt" < '2026-03-10 00:00:00 UTC'::timestamptz)
+
+CREATE OR REPLACE FUNCTION "Posts_insert_trigger"()
+    RETURNS trigger AS $$
+    BEGIN
+        IF (NEW."createdAt" >= '2026-03-08 00:00:00 UTC'::timestamptz AND NEW."createdAt" < '2026-03-09 00:00:00 UTC'::timestamptz) THEN
+            INSERT INTO "public"."Posts_20260308" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= '2026-03-09 00:00:00 UTC'::timestamptz AND NEW."createdAt" < '2026-03-10 00:00:00 UTC'::timestamptz) THEN
+            INSERT INTO "public"."Posts_20260309" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= '2026-03-07 00:00:00 UTC'::timestamptz AND NEW."createdAt" < '2026-03-08 00:00:00 UTC'::timestamptz) THEN
+            INSERT INTO "public"."Posts_20260307" VALUES (NEW.*);
+        ELSE
+            RAISE EXCEPTION 'Date out of range. Ensure partitions are created.';
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
@@ -0,0 +1,27 @@
+-- Posts_20260307
+FOR VALUES FROM ('2026-03-07 00:00:00-05:00') TO ('2026-03-08 00:00:00-05:00')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-03-07 00:00:00-05:00'::timestamptz AND "createdAt" < '2026-03-08 00:00:00-05:00'::timestamptz)
+
+-- Posts_20260308
+FOR VALUES FROM ('2026-03-08 00:00:00-05:00') TO ('2026-03-09 00:00:00-04:00')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-03-08 00:00:00-05:00'::timestamptz AND "createdAt" < '2026-03-09 00:00:00-04:00'::timestamptz)
+
+-- Posts_20260309
+FOR VALUES FROM ('2026-03-09 00:00:00-04:00') TO ('2026-03-10 00:00:00-04:00')
+CHECK ("createdAt" IS NOT NULL AND "createdAt" >= '2026-03-09 00:00:00-04:00'::timestamptz AND "createdAt" < '2026-03-10 00:00:00-04:00'::timestamptz)
+
+CREATE OR REPLACE FUNCTION "Posts_insert_trigger"()
+    RETURNS trigger AS $$
+    BEGIN
+        IF (NEW."createdAt" >= '2026-03-08 00:00:00-05:00'::timestamptz AND NEW."createdAt" < '2026-03-09 00:00:00-04:00'::timestamptz) THEN
+            INSERT INTO "public"."Posts_20260308" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= '2026-03-09 00:00:00-04:00'::timestamptz AND NEW."createdAt" < '2026-03-10 00:00:00-04:00'::timestamptz) THEN
+            INSERT INTO "public"."Posts_20260309" VALUES (NEW.*);
+        ELSIF (NEW."createdAt" >= '2026-03-07 00:00:00-05:00'::timestamptz AND NEW."createdAt" < '2026-03-08 00:00:00-05:00'::timestamptz) THEN
+            INSERT INTO "public"."Posts_20260307" VALUES (NEW.*);
+        ELSE
+            RAISE EXCEPTION 'Date out of range. Ensure partitions are created.';
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
@@ -0,0 +1,12 @@
+CREATE OR REPLACE FUNCTION "Posts_sync_trigger"()
+    RETURNS trigger AS $$
+    BEGIN
+        IF TG_OP = 'UPDATE' OR TG_OP = 'DELETE' THEN
+            DELETE FROM "public"."Posts_intermediate" WHERE "Id" = OLD."Id";
+        END IF;
+        IF TG_OP = 'INSERT' OR TG_OP = 'UPDATE' THEN
+            INSERT INTO "public"."Posts_intermediate" ("Id", "createdAt") VALUES (NEW."Id", NEW."createdAt");
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
@@ -0,0 +1,16 @@
+CREATE OR REPLACE FUNCTION "Posts_sync_trigger"()
+    RETURNS trigger AS $$
+    BEGIN
+        IF TG_OP = 'UPDATE' OR TG_OP = 'DELETE' THEN
+            IF OLD."createdAt" >= '2026-01-01'::date AND OLD."createdAt" < '2026-01-04'::date THEN
+                DELETE FROM "public"."Posts_intermediate" WHERE "Id" = OLD."Id";
+            END IF;
+        END IF;
+        IF TG_OP = 'INSERT' OR TG_OP = 'UPDATE' THEN
+            IF NEW."createdAt" >= '2026-01-01'::date AND NEW."createdAt" < '2026-01-04'::date THEN
+                INSERT INTO "public"."Posts_intermediate" ("Id", "createdAt") VALUES (NEW."Id", NEW."createdAt");
+            END IF;
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
@@ -0,0 +1,20 @@
+CREATE OR REPLACE FUNCTION "Posts_sync_trigger"()
+    RETURNS trigger AS $$
+    BEGIN
+        IF TG_OP = 'UPDATE' OR TG_OP = 'DELETE' THEN
+            IF OLD."createdAt" >= '2026-01-01'::date AND OLD."createdAt" < '2026-01-02'::date THEN
+                DELETE FROM "public"."Posts_20260101" WHERE "Id" = OLD."Id";
+            ELSIF OLD."createdAt" >= '2026-01-02'::date AND OLD."createdAt" < '2026-01-03'::date THEN
+                DELETE FROM "public"."Posts_20260102" WHERE "Id" = OLD."Id";
+            END IF;
+        END IF;
+        IF TG_OP = 'INSERT' OR TG_OP = 'UPDATE' THEN
+            IF NEW."createdAt" >= '2026-01-01'::date AND NEW."createdAt" < '2026-01-02'::date THEN
+                INSERT INTO "public"."Posts_20260101" ("Id", "createdAt") VALUES (NEW."Id", NEW."createdAt");
+            ELSIF NEW."createdAt" >= '2026-01-02'::date AND NEW."createdAt" < '2026-01-03'::date THEN
+                INSERT INTO "public"."Posts_20260102" ("Id", "createdAt") VALUES (NEW."Id", NEW."createdAt");
+            END IF;
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
@@ -0,0 +1,189 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// throttlePollInterval is how often to check again while waiting
+const throttlePollInterval = time.Second
+
+// fillThrottle pauses fill while replicas are behind or the WAL rate is
+// too high. A nil fillThrottle never waits.
+type fillThrottle struct {
+	db            *sql.DB
+	replicas      []*sql.DB
+	maxReplicaLag time.Duration
+	maxWALRate    int64
+	log           io.Writer
+
+	mu      sync.Mutex
+	lastLSN string
+	lastAt  time.Time
+}
+
+// newFillThrottle returns nil unless there's a max replica lag or WAL rate
+func newFillThrottle(ctx context.Context, db *sql.DB, opts FillOptions) (*fillThrottle, error) {
+	if len(opts.ReplicaURLs) > 0 && opts.MaxReplicaLag == 0 {
+		return nil, abort("--replica-url requires --max-replica-lag")
+	}
+	if opts.MaxReplicaLag == 0 && opts.MaxWALRate == 0 {
+		return nil, nil
+	}
+	if opts.MaxReplicaLag < 0 {
+		return nil, abort(fmt.Sprintf("Invalid max replica lag: %s", opts.MaxReplicaLag))
+	}
+	if opts.MaxWALRate < 0 {
+		return nil, abort(fmt.Sprintf("Invalid max WAL rate: %d", opts.MaxWALRate))
+	}
+
+	serverVersionNum, err := fetchServerVersionNum(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if serverVersionNum < 100000 {
+		return nil, abort("--max-replica-lag and --max-wal-rate require Postgres 10+")
+	}
+
+	t := &fillThrottle{db: db, maxReplicaLag: opts.MaxReplicaLag, maxWALRate: opts.MaxWALRate, log: opts.Log}
+	for _, url := range opts.ReplicaURLs {
+		replica, err := sql.Open("postgres", url)
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+		replica.SetMaxOpenConns(1)
+		t.replicas = append(t.replicas, replica)
+	}
+	return t, nil
+}
+
+func (t *fillThrottle) Close() {
+	if t == nil {
+		return
+	}
+	for _, replica := range t.replicas {
+		replica.Close()
+	}
+}
+
+// Wait blocks until replica lag and the WAL rate are below their limits
+func (t *fillThrottle) Wait(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	// one check at a time for parallel jobs
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	waiting := false
+	for {
+		reason, err := t.check(ctx)
+		if err != nil {
+			return err
+		}
+		if reason == "" {
+			return nil
+		}
+
+		// log once per pause
+		if !waiting {
+			logSQL(t.log, fmt.Sprintf("/* waiting for %s */", reason))
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(throttlePollInterval):
+		}
+	}
+}
+
+// check returns why fill should wait, or an empty string if it shouldn't
+func (t *fillThrottle) check(ctx context.Context) (string, error) {
+	if t.maxReplicaLag > 0 {
+		lag, err := t.replicaLag(ctx)
+		if err != nil {
+			return "", err
+		}
+		if lag > t.maxReplicaLag {
+			return fmt.Sprintf("replica lag (%s)", lag.Round(time.Second)), nil
+		}
+	}
+
+	if t.maxWALRate > 0 {
+		rate, err := t.walRate(ctx)
+		if err != nil {
+			return "", err
+		}
+		if rate > t.maxWALRate {
+			return fmt.Sprintf("WAL rate (%d bytes/s)", rate), nil
+		}
+	}
+
+	return "", nil
+}
+
+// replicaLag is the max replay lag of standbys of the primary and
+// replicas from URLs, which may include cascading replicas
+func (t *fillThrottle) replicaLag(ctx context.Context) (time.Duration, error) {
+	var seconds float64
+	var hidden int
+	// replay_lag is null when a standby is caught up, and state is
+	// only null when the user can't see the standby's stats
+	err := t.db.QueryRowContext(ctx, "SELECT COALESCE(EXTRACT(EPOCH FROM MAX(replay_lag)), 0), COUNT(*) FILTER (WHERE state IS NULL) FROM pg_stat_replication").Scan(&seconds, &hidden)
+	if err != nil {
+		return 0, err
+	}
+	if hidden > 0 {
+		return 0, abort("--max-replica-lag requires the pg_monitor role to see replica lag")
+	}
+	lag := time.Duration(seconds * float64(time.Second))
+
+	// the replay timestamp is old when nothing is written, so
+	// there's no lag when everything received has been replayed
+	query := `
+SELECT
+  CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+  ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0)
+  END
+  `
+	for _, replica := range t.replicas {
+		err := replica.QueryRowContext(ctx, query).Scan(&seconds)
+		if err != nil {
+			return 0, err
+		}
+		lag = max(lag, time.Duration(seconds*float64(time.Second)))
+	}
+
+	return lag, nil
+}
+
+// walRate is the bytes of WAL written per second since the last check
+func (t *fillThrottle) walRate(ctx context.Context) (int64, error) {
+	var lsn string
+	var bytes int64
+	err := t.db.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()::text, COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), NULLIF($1, '')::pg_lsn), 0)::bigint", t.lastLSN).Scan(&lsn, &bytes)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+
+	var rate int64
+	if t.lastLSN != "" {
+		elapsed := now.Sub(t.lastAt).Seconds()
+		if elapsed > 0 {
+			rate = int64(float64(bytes) / elapsed)
+		}
+	}
+
+	t.lastLSN = lsn
+	t.lastAt = now
+	return rate, nil
+}
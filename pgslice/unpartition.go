@@ -0,0 +1,180 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+)
+
+type UnpartitionOptions struct {
+	Table       string
+	BatchSize   int
+	Sleep       time.Duration
+	LockTimeout string
+	DryRun      bool
+	Log         io.Writer
+}
+
+// Unpartition replaces a partitioned table with a regular table. The
+// intermediate table is created and synced, filled in batches, and
+// swapped in, and the partitioned table becomes the retired table.
+// Running it again after a failure resumes the fill.
+func Unpartition(ctx context.Context, db *sql.DB, opts UnpartitionOptions) error {
+	table := createTable(opts.Table)
+	intermediateTable := table.IntermediateTable()
+	retiredTable := table.RetiredTable()
+
+	exists, err := table.Exists(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	exists, err = retiredTable.Exists(ctx, db)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return abort(fmt.Sprintf("Table already exists: %s", retiredTable.FullName()))
+	}
+
+	// resume when the intermediate table and sync trigger exist
+	intermediateExists, err := intermediateTable.Exists(ctx, db)
+	if err != nil {
+		return err
+	}
+	syncing, err := table.TriggerExists(ctx, db, table.SyncTriggerName())
+	if err != nil {
+		return err
+	}
+	if intermediateExists && !syncing {
+		return abort(fmt.Sprintf("Table already exists: %s", intermediateTable.FullName()))
+	}
+	if syncing && !intermediateExists {
+		return abort(fmt.Sprintf("Trigger already exists: %s", table.SyncTriggerName()))
+	}
+	resume := intermediateExists && syncing
+
+	if resume {
+		// a partitioned intermediate table is from prep
+		intermediateSettings, err := fetchSettings(ctx, db, table, intermediateTable)
+		if err != nil {
+			return err
+		}
+		if intermediateSettings.Partitioned() {
+			return abort(fmt.Sprintf("Table already exists: %s", intermediateTable.FullName()))
+		}
+	}
+
+	settings, err := fetchSettings(ctx, db, table, table)
+	if err != nil {
+		return err
+	}
+
+	if !settings.Partitioned() {
+		return abort(fmt.Sprintf("No settings found: %s", table.FullName()))
+	}
+	// the sync trigger doesn't see rows routed by the insert trigger
+	if !settings.Declarative {
+		return abort("Use upgrade before unpartition for trigger-based partitioning")
+	}
+
+	serverVersionNum, err := fetchServerVersionNum(ctx, db)
+	if err != nil {
+		return err
+	}
+	if serverVersionNum < 110000 {
+		return abort("unpartition requires Postgres 11+")
+	}
+
+	partitions, err := table.LeafPartitions(ctx, db)
+	if err != nil {
+		return err
+	}
+	if len(partitions) == 0 {
+		return abort("No partitions")
+	}
+
+	// partitions have the primary key, foreign keys, and indexes, and
+	// index definitions on the partitioned table are ON ONLY
+	schemaTable := partitions[len(partitions)-1]
+	primaryKey, err := schemaTable.PrimaryKey(ctx, db)
+	if err != nil {
+		return err
+	}
+	if len(primaryKey) == 0 {
+		return abort("No primary key")
+	}
+	fkDefs, err := schemaTable.ForeignKeys(ctx, db)
+	if err != nil {
+		return err
+	}
+	indexDefs, err := schemaTable.IndexDefs(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	executeOptions := ExecuteOptions{DryRun: opts.DryRun, Log: opts.Log}
+
+	plan := &Plan{}
+	plan.Add("Create intermediate table", fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE INCLUDING COMMENTS);", quoteTable(intermediateTable), quoteTable(table)), newLock(AccessShare, table))
+	plan.Add("Add primary key", fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", quoteTable(intermediateTable), quoteColumns(primaryKey)), newLock(AccessExclusive, intermediateTable))
+	for _, def := range indexDefs {
+		plan.Add("Copy index", makeIndexDef(def, intermediateTable), newLock(Share, intermediateTable))
+	}
+	for _, def := range fkDefs {
+		plan.Add("Copy foreign key", makeFkDef(def, intermediateTable), makeFkLocks(def, intermediateTable)...)
+	}
+	// copy changes made during the fill
+	columns, err := table.Columns(ctx, db)
+	if err != nil {
+		return err
+	}
+	addSyncTrigger(plan, table, []syncTarget{{table: intermediateTable}}, settings, primaryKey, columns)
+
+	if resume {
+		logSQL(opts.Log, "/* resuming */")
+	} else {
+		err = plan.Execute(ctx, db, executeOptions)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.DryRun {
+		logSQL(opts.Log, fmt.Sprintf("/* fill %s and swap */", intermediateTable.FullName()))
+		return nil
+	}
+
+	fillOptions := FillOptions{
+		Table:       intermediateTable.FullName(),
+		SourceTable: table.FullName(),
+		DestTable:   intermediateTable.FullName(),
+		BatchSize:   opts.BatchSize,
+		Sleep:       opts.Sleep,
+		Log:         opts.Log,
+	}
+	if resume {
+		checkpoint, err := FillCheckpoint(ctx, db, fillOptions)
+		if err != nil {
+			return err
+		}
+		fillOptions.Resume = checkpoint != nil
+	}
+
+	err = Fill(ctx, db, fillOptions)
+	if err != nil {
+		return err
+	}
+
+	// drops the sync trigger and updates sequence owners
+	swapPlan, err := Swap(ctx, db, SwapOptions{Table: table.FullName(), LockTimeout: opts.LockTimeout})
+	if err != nil {
+		return err
+	}
+	return swapPlan.Execute(ctx, db, executeOptions)
+}
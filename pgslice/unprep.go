@@ -0,0 +1,46 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type UnprepOptions struct {
+	Table string
+}
+
+func Unprep(ctx context.Context, db *sql.DB, opts UnprepOptions) (*Plan, error) {
+	table := createTable(opts.Table)
+	intermediateTable := table.IntermediateTable()
+	triggerName := table.TriggerName()
+
+	exists, err := intermediateTable.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", intermediateTable.FullName()))
+	}
+
+	plan := &Plan{}
+
+	tableExists, err := table.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if tableExists {
+		syncing, err := table.TriggerExists(ctx, db, table.SyncTriggerName())
+		if err != nil {
+			return nil, err
+		}
+		if syncing {
+			addDropSyncTrigger(plan, table)
+		}
+	}
+
+	plan.Add("Drop intermediate table", fmt.Sprintf("DROP TABLE %s CASCADE;", quoteTable(intermediateTable)), newLock(AccessExclusive, intermediateTable))
+	plan.Add("Drop insert trigger function", fmt.Sprintf("DROP FUNCTION IF EXISTS %s();", quoteIdent(triggerName)))
+
+	return plan, nil
+}
@@ -0,0 +1,56 @@
+package pgslice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type UnswapOptions struct {
+	Table string
+}
+
+func Unswap(ctx context.Context, db *sql.DB, opts UnswapOptions) (*Plan, error) {
+	table := createTable(opts.Table)
+	intermediateTable := table.IntermediateTable()
+	retiredTable := table.RetiredTable()
+
+	exists, err := table.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	exists, err = retiredTable.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", retiredTable.FullName()))
+	}
+
+	exists, err = intermediateTable.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, abort(fmt.Sprintf("Table already exists: %s", intermediateTable.FullName()))
+	}
+
+	plan := &Plan{}
+	plan.Add("Rename table to intermediate table", fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", quoteTable(table), quoteNoSchema(intermediateTable)), newLock(AccessExclusive, table))
+	plan.Add("Rename retired table to table", fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", quoteTable(retiredTable), quoteNoSchema(table)), newLock(AccessExclusive, retiredTable))
+
+	sequences, err := table.Sequences(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sequence := range sequences {
+		plan.Add("Update sequence owner", fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s;", quoteIdent(sequence.Name), quoteTable(table), quoteIdent(sequence.Column)))
+	}
+
+	return plan, nil
+}
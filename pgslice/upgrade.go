@@ -0,0 +1,190 @@
+package pgslice
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type UpgradeOptions struct {
+	Table       string
+	LockTimeout string
+}
+
+// Upgrade converts trigger-based partitioning to declarative partitioning.
+// Missing indexes and constraints that exclude nulls are added to the
+// children without blocking writes. Then the table is replaced with a
+// partitioned table, and its children are attached using their CHECK
+// constraints, so Postgres doesn't need to scan them.
+func Upgrade(ctx context.Context, db *sql.DB, opts UpgradeOptions) (*Plan, error) {
+	table := createTable(opts.Table)
+	oldTable := table.IntermediateTable()
+	triggerName := table.TriggerName()
+
+	exists, err := table.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, abort(fmt.Sprintf("Table not found: %s", table.FullName()))
+	}
+
+	exists, err = oldTable.Exists(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, abort(fmt.Sprintf("Table already exists: %s", oldTable.FullName()))
+	}
+
+	settings, err := fetchSettings(ctx, db, table, table)
+	if err != nil {
+		return nil, err
+	}
+
+	if !settings.Partitioned() {
+		return nil, abort(fmt.Sprintf("No settings found: %s", table.FullName()))
+	}
+	if settings.Declarative {
+		return nil, abort(fmt.Sprintf("Already declarative: %s", table.FullName()))
+	}
+
+	serverVersionNum, err := fetchServerVersionNum(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if serverVersionNum < 110000 {
+		return nil, abort("upgrade requires Postgres 11+")
+	}
+
+	// rows in the parent table wouldn't be in a partition
+	var hasRows bool
+	err = db.QueryRowContext(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM ONLY %s)", quoteTable(table))).Scan(&hasRows)
+	if err != nil {
+		return nil, err
+	}
+	if hasRows {
+		return nil, abort(fmt.Sprintf("Rows found in parent table: %s", table.FullName()))
+	}
+
+	children, err := table.Partitions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := table.PartitionBounds(ctx, db, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultPartition := table.DefaultPartition()
+	hasDefault := false
+	for _, child := range children {
+		ranged := slices.ContainsFunc(partitions, func(p PartitionRange) bool { return p.Table == child })
+		if ranged {
+			continue
+		}
+		if child != defaultPartition {
+			return nil, abort(fmt.Sprintf("No range found for partition: %s", child.FullName()))
+		}
+		hasDefault = true
+	}
+
+	indexDefs, err := table.IndexDefs(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	sequences, err := table.Sequences(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+
+	// partitions from trigger-based partitioning on Postgres 11+ don't
+	// have the parent's indexes, so build any that are missing without
+	// blocking writes, and the partitioned table's indexes attach them
+	for _, child := range children {
+		childIndexDefs, err := child.IndexDefs(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		existing := make([]string, len(childIndexDefs))
+		for i, def := range childIndexDefs {
+			existing[i] = makeIndexDef(def, child)
+		}
+		for _, def := range indexDefs {
+			childDef := makeIndexDef(def, child)
+			if contains(existing, childDef) {
+				continue
+			}
+			plan.AddWithoutTransaction("Create index", strings.Replace(childDef, " INDEX ON ", " INDEX CONCURRENTLY ON ", 1), newLock(ShareUpdateExclusive, child))
+		}
+	}
+
+	// range constraints from trigger-based partitioning don't exclude
+	// nulls like the partition constraint, so Postgres would scan the
+	// tables when attaching without another constraint
+	for _, partition := range partitions {
+		notNull, err := partition.Table.ColumnNotNull(ctx, db, settings.Column)
+		if err != nil {
+			return nil, err
+		}
+		if notNull {
+			continue
+		}
+
+		// may exist without being validated from an earlier run
+		constraintName := fmt.Sprintf("%s_%s_not_null", partition.Table.Name, settings.Column)
+		exists, err := partition.Table.ConstraintExists(ctx, db, constraintName)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			plan.AddWithoutTransaction("Add check constraint", fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s IS NOT NULL) NOT VALID;", quoteTable(partition.Table), quoteIdent(constraintName), quoteIdent(settings.Column)), newLock(AccessExclusive, partition.Table))
+		}
+		plan.AddWithoutTransaction("Validate check constraint", fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s;", quoteTable(partition.Table), quoteIdent(constraintName)), newLock(ShareUpdateExclusive, partition.Table))
+	}
+
+	plan.Add("Set lock timeout", fmt.Sprintf("SET LOCAL lock_timeout = '%s';", cmp.Or(opts.LockTimeout, defaultLockTimeout)))
+
+	// rename first to block inserts while the partitions move
+	plan.Add("Rename table to intermediate table", fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", quoteTable(table), quoteNoSchema(oldTable)), newLock(AccessExclusive, table))
+	plan.Add("Create partitioned table", fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE INCLUDING COMMENTS) PARTITION BY RANGE (%s);", quoteTable(table), quoteTable(oldTable), quoteIdent(settings.Column)), newLock(AccessShare, oldTable))
+
+	// partitions have the same indexes now, so they're attached instead of built
+	for _, def := range indexDefs {
+		plan.Add("Copy index", makeIndexDef(def, table), newLock(Share, table))
+	}
+
+	for _, partition := range partitions {
+		plan.Add("Remove partition", fmt.Sprintf("ALTER TABLE %s NO INHERIT %s;", quoteTable(partition.Table), quoteTable(oldTable)), newLock(AccessExclusive, partition.Table), newLock(ShareUpdateExclusive, oldTable))
+		// the constraint lets Postgres skip scanning the table
+		plan.Add("Attach partition", fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (%s) TO (%s);", quoteTable(table), quoteTable(partition.Table), settings.SQL(partition.Start, false), settings.SQL(partition.End, false)), newLock(AccessExclusive, table), newLock(AccessExclusive, partition.Table))
+	}
+
+	// attach last so it isn't scanned for rows in other partitions
+	if hasDefault {
+		plan.Add("Remove partition", fmt.Sprintf("ALTER TABLE %s NO INHERIT %s;", quoteTable(defaultPartition), quoteTable(oldTable)), newLock(AccessExclusive, defaultPartition), newLock(ShareUpdateExclusive, oldTable))
+		plan.Add("Attach default partition", fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s DEFAULT;", quoteTable(table), quoteTable(defaultPartition)), newLock(AccessExclusive, table), newLock(AccessExclusive, defaultPartition))
+	}
+
+	plan.Add("Drop insert trigger", fmt.Sprintf("DROP TRIGGER %s ON %s;", quoteIdent(triggerName), quoteTable(oldTable)), newLock(AccessExclusive, oldTable))
+	plan.Add("Drop insert trigger function", fmt.Sprintf("DROP FUNCTION %s();", quoteIdent(triggerName)))
+
+	for _, sequence := range sequences {
+		plan.Add("Update sequence owner", fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s;", quoteIdent(sequence.Name), quoteTable(table), quoteIdent(sequence.Column)))
+	}
+
+	// move settings from the trigger to the table
+	settings.Declarative = true
+	addSaveSettings(plan, table, triggerName, settings)
+
+	// fails if other objects depend on the table
+	plan.Add("Drop old table", fmt.Sprintf("DROP TABLE %s;", quoteTable(oldTable)), newLock(AccessExclusive, oldTable))
+
+	return plan, nil
+}